@@ -0,0 +1,10 @@
+// Package assets embeds the built-in tables of the generators, so they are available
+// with no files on disk. Load them with tables.LoadDefault.
+package assets
+
+import "embed"
+
+// FS holds every JSON document of this directory.
+//
+//go:embed *.json
+var FS embed.FS
@@ -3,23 +3,24 @@ package dice
 import "fmt"
 
 // Roll evaluates the expression, applies the modifiers, and returns the sum.
+// For concat dice the modifiers adjust the dice positionally instead.
 // It is safe for concurrent use.
 func (m *Manager) Roll(expr string, mods ...int) (int, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if err := m.roll(expr); err != nil {
+	intrpr, _, err := m.evaluate(expr, nil, mods)
+	if err != nil {
 		return 0, fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
 	}
-	intr := &stdInterpreter{}
-	intrpr, err := intr.interpret(m.rollState)
+	return intrpr.sum, nil
+}
+
+// RollResult is like Roll but also returns the dice of this very call, which Result
+// cannot guarantee when the Manager is shared between goroutines.
+func (m *Manager) RollResult(expr string, mods ...int) (int, Result, error) {
+	intrpr, res, err := m.evaluate(expr, nil, mods)
 	if err != nil {
-		return 0, fmt.Errorf("roll state recovery %q %v failed: %w", expr, mods, err)
+		return 0, Result{}, fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
 	}
-	sum := intrpr.sum
-	for _, dm := range mods {
-		sum += dm
-	}
-	return sum, nil
+	return intrpr.sum, Result{dice: res.dice, raw: res.raw, codes: res.codes, explosions: res.explosions}, nil
 }
 
 // Roll uses the default manager to evaluate an expression.
@@ -27,23 +28,28 @@ func Roll(expr string, mods ...int) (int, error) {
 	return defaultManager.Roll(expr, mods...)
 }
 
+// RollCode evaluates the expression like Roll but returns the result as a string code.
+// Concat dice keep their leading zeros ("3D6" -> "364", "2D10" -> "07").
+func (m *Manager) RollCode(expr string, mods ...int) (string, error) {
+	intrpr, _, err := m.evaluate(expr, nil, mods)
+	if err != nil {
+		return "", fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
+	}
+	return intrpr.code, nil
+}
+
+// RollCode uses the default manager to evaluate an expression as a string code.
+func RollCode(expr string, mods ...int) (string, error) {
+	return defaultManager.RollCode(expr, mods...)
+}
+
 // MustRoll is like Roll but panics on error.
 func (m *Manager) MustRoll(expr string, dm ...int) int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if err := m.roll(expr); err != nil {
-		panic(err)
-	}
-	intr := &stdInterpreter{}
-	intrpr, err := intr.interpret(m.rollState)
+	intrpr, _, err := m.evaluate(expr, nil, dm)
 	if err != nil {
 		panic(err)
 	}
-	sum := intrpr.sum
-	for _, val := range dm {
-		sum += val
-	}
-	return sum
+	return intrpr.sum
 }
 
 // MustRoll uses the default manager and panics on error.
@@ -54,19 +60,7 @@ func MustRoll(expr string, dm ...int) int {
 // D66 rolls two six-sided dice and returns a two-digit string (each digit 0‑9).
 // Optional modifiers are applied to the first and second die respectively.
 func (m *Manager) D66(mods ...int) string {
-	r1 := m.MustRoll("1d6")
-	r2 := m.MustRoll("1d6")
-	for i, mod := range mods {
-		switch i {
-		case 0:
-			r1 += mod
-		case 1:
-			r2 += mod
-		}
-	}
-	r1 = setBounds(r1, 0, 9)
-	r2 = setBounds(r2, 0, 9)
-	return fmt.Sprintf("%d%d", r1, r2)
+	return m.mustDigits("D66", mods...)
 }
 
 // D66 uses the default manager.
@@ -76,9 +70,7 @@ func D66(mods ...int) string {
 
 // Flux returns (first die minus second die) plus any modifiers.
 func (m *Manager) Flux(dm ...int) int {
-	r1 := m.MustRoll("1d6")
-	r2 := m.MustRoll("1d6")
-	return r1 - r2 + sum(dm...)
+	return m.mustFlux(m.FluxDice, dm)
 }
 
 // Flux uses the default manager.
@@ -88,9 +80,7 @@ func Flux(dm ...int) int {
 
 // FluxGood returns (higher die minus lower die) plus modifiers.
 func (m *Manager) FluxGood(dm ...int) int {
-	r1 := m.MustRoll("1d6")
-	r2 := m.MustRoll("1d6")
-	return max(r1, r2) - min(r1, r2) + sum(dm...)
+	return m.mustFlux(m.FluxGoodDice, dm)
 }
 
 // FluxGood uses the default manager.
@@ -100,9 +90,7 @@ func FluxGood(dm ...int) int {
 
 // FluxBad returns (lower die minus higher die) plus modifiers.
 func (m *Manager) FluxBad(dm ...int) int {
-	r1 := m.MustRoll("1d6")
-	r2 := m.MustRoll("1d6")
-	return min(r1, r2) - max(r1, r2) + sum(dm...)
+	return m.mustFlux(m.FluxBadDice, dm)
 }
 
 // FluxBad uses the default manager.
@@ -110,10 +98,10 @@ func FluxBad(dm ...int) int {
 	return defaultManager.FluxBad(dm...)
 }
 
-// Variance returns a random float64 in the range [0.0, 1.0] using the same random source.
+// Variance returns a random float64 in the range [0.0, 1.0] in steps of 0.001 using
+// the same random source. VarianceResolution takes another step count.
 func (m *Manager) Variance() float64 {
-	r := m.MustRoll("1d1001-1")
-	return float64(r) / 1000
+	return m.VarianceResolution(DefaultVarianceResolution)
 }
 
 // Variance uses the default manager.
@@ -121,6 +109,15 @@ func Variance() float64 {
 	return defaultManager.Variance()
 }
 
+// mustFlux rolls a two-dice d6 Flux variant and panics on error, like MustRoll.
+func (m *Manager) mustFlux(flux func(count, sides int, dm ...int) (int, error), dm []int) int {
+	v, err := flux(2, 6, dm...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
 // sum is a helper that adds all integers in a slice.
 func sum(values ...int) int {
 	s := 0
@@ -0,0 +1,392 @@
+package dice
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	opAdd = "+"
+	opSub = "-"
+	opMul = "*"
+	opDiv = "/"
+
+	funcMax   = "max"
+	funcMin   = "min"
+	funcAbs   = "abs"
+	funcFloor = "floor"
+	funcCeil  = "ceil"
+	funcRound = "round"
+)
+
+// functionArity lists the functions the grammar knows, with their minimum and maximum
+// argument count (-1 for no maximum).
+var functionArity = map[string][2]int{
+	funcMax:   {1, -1},
+	funcMin:   {1, -1},
+	funcAbs:   {1, 1},
+	funcFloor: {1, 1},
+	funcCeil:  {1, 1},
+	funcRound: {1, 1},
+}
+
+// node is a single element of a parsed expression tree.
+type node interface {
+	eval(e *env) (int, error)
+	String() string
+	precedence() int
+}
+
+// env is what a node is evaluated against: the final value of every dice group,
+// indexed by group, and the variables. Every variable read is kept in used.
+type env struct {
+	groups []int
+	vars   Resolver
+	used   map[string]int
+}
+
+// lookup resolves a variable and records its value.
+func (e *env) lookup(name string) (int, error) {
+	if e.vars == nil {
+		return 0, fmt.Errorf("undefined variable %s%s: no variables given", variablePrefix, name)
+	}
+	v, ok := e.vars.Resolve(name)
+	if !ok {
+		return 0, fmt.Errorf("undefined variable %s%s", variablePrefix, name)
+	}
+	if e.used == nil {
+		e.used = make(map[string]int)
+	}
+	e.used[name] = v
+	return v, nil
+}
+
+const (
+	precedenceSum     = 1
+	precedenceProduct = 2
+	precedenceUnary   = 3
+	precedenceAtom    = 4
+)
+
+type numberNode struct {
+	value int
+}
+
+func (n numberNode) eval(*env) (int, error) { return n.value, nil }
+func (n numberNode) String() string         { return strconv.Itoa(n.value) }
+func (n numberNode) precedence() int        { return precedenceAtom }
+
+// varNode is a named variable, resolved when the expression is rolled.
+type varNode struct {
+	name string
+}
+
+func (n varNode) eval(e *env) (int, error) { return e.lookup(n.name) }
+func (n varNode) String() string           { return variablePrefix + n.name }
+func (n varNode) precedence() int          { return precedenceAtom }
+
+// diceNode refers to a dice group of the expression by its index.
+type diceNode struct {
+	group *diceGroup
+}
+
+func (n diceNode) eval(e *env) (int, error) { return e.groups[n.group.index], nil }
+func (n diceNode) String() string           { return n.group.String() }
+func (n diceNode) precedence() int {
+	if n.group.constant != 0 && len(n.group.chain) == 0 {
+		return precedenceSum
+	}
+	return precedenceAtom
+}
+
+type negNode struct {
+	operand node
+}
+
+func (n negNode) eval(e *env) (int, error) {
+	v, err := n.operand.eval(e)
+	return -v, err
+}
+func (n negNode) String() string  { return "-" + wrap(n.operand, precedenceUnary, false) }
+func (n negNode) precedence() int { return precedenceUnary }
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(e *env) (int, error) {
+	l, err := n.left.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	return applyOperator(n.op, l, r)
+}
+
+func (n binaryNode) String() string {
+	p := n.precedence()
+	return wrap(n.left, p, false) + n.op + wrap(n.right, p, true)
+}
+
+func (n binaryNode) precedence() int {
+	if n.op == opAdd || n.op == opSub {
+		return precedenceSum
+	}
+	return precedenceProduct
+}
+
+// applyOperator evaluates a single arithmetic operation. Division truncates toward zero.
+func applyOperator(op string, l, r int) (int, error) {
+	switch op {
+	case opAdd:
+		return l + r, nil
+	case opSub:
+		return l - r, nil
+	case opMul:
+		return l * r, nil
+	case opDiv:
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	default:
+		return 0, fmt.Errorf("unknown operator %q", op)
+	}
+}
+
+type funcNode struct {
+	name string
+	args []node
+}
+
+func (n funcNode) eval(e *env) (int, error) {
+	if div, ok := n.roundedDivision(); ok {
+		l, err := div.left.eval(e)
+		if err != nil {
+			return 0, err
+		}
+		r, err := div.right.eval(e)
+		if err != nil {
+			return 0, err
+		}
+		return roundDivision(n.name, l, r)
+	}
+	values := make([]int, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(e)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = v
+	}
+	return applyFunction(n.name, values)
+}
+
+// roundedDivision returns the division a rounding function applies its mode to.
+func (n funcNode) roundedDivision() (binaryNode, bool) {
+	switch n.name {
+	case funcFloor, funcCeil, funcRound:
+		div, ok := n.args[0].(binaryNode)
+		return div, ok && div.op == opDiv
+	}
+	return binaryNode{}, false
+}
+
+func (n funcNode) String() string {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	return n.name + "(" + strings.Join(args, ",") + ")"
+}
+
+func (n funcNode) precedence() int { return precedenceAtom }
+
+// applyFunction evaluates max, min and abs. Rounding functions are the identity on
+// anything but a division, since every other value is already an integer.
+func applyFunction(name string, values []int) (int, error) {
+	switch name {
+	case funcMax:
+		return slices.Max(values), nil
+	case funcMin:
+		return slices.Min(values), nil
+	case funcAbs:
+		return max(values[0], -values[0]), nil
+	case funcFloor, funcCeil, funcRound:
+		return values[0], nil
+	default:
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+// roundDivision divides l by r using the rounding mode of the named function.
+// round rounds halves away from zero.
+func roundDivision(name string, l, r int) (int, error) {
+	if r == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	q, rem := l/r, l%r
+	if rem == 0 {
+		return q, nil
+	}
+	negative := (rem < 0) != (r < 0)
+	switch name {
+	case funcFloor:
+		if negative {
+			return q - 1, nil
+		}
+		return q, nil
+	case funcCeil:
+		if negative {
+			return q, nil
+		}
+		return q + 1, nil
+	case funcRound:
+		if 2*max(rem, -rem) < max(r, -r) {
+			return q, nil
+		}
+		if negative {
+			return q - 1, nil
+		}
+		return q + 1, nil
+	default:
+		return 0, fmt.Errorf("unknown rounding function %q", name)
+	}
+}
+
+// walk visits n and all of its descendants, parents first.
+func walk(n node, visit func(node)) {
+	visit(n)
+	switch nn := n.(type) {
+	case negNode:
+		walk(nn.operand, visit)
+	case binaryNode:
+		walk(nn.left, visit)
+		walk(nn.right, visit)
+	case funcNode:
+		for _, arg := range nn.args {
+			walk(arg, visit)
+		}
+	}
+}
+
+// wrap renders a child node, adding parentheses when its precedence requires them.
+// Right operands of the same precedence are wrapped as well, since - and / do not associate.
+func wrap(n node, parent int, right bool) string {
+	if n.precedence() < parent || (right && n.precedence() == parent) {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// diceGroup is a set of identical dice rolled together with their own modifier chain.
+type diceGroup struct {
+	index    int
+	diceType string
+	count    int
+	sides    int
+	explode  explosion
+	custom   string
+	faces    []Face
+	success  *countSuccesses
+	constant int
+	chain    []mod
+	dicepool dicepool
+	mods     []mod
+}
+
+// build creates the dicepool and the full, priority sorted modifier list.
+func (g *diceGroup) build() error {
+	dice := make([]die, g.count)
+	for i := range g.count {
+		if g.faces != nil {
+			dice[i] = newCustomDie(g.faces)
+			continue
+		}
+		dice[i] = newDie(g.sides).withExplosion(g.explode)
+	}
+	g.dicepool = newDicepool(dice...)
+
+	allMods := slices.Clone(g.chain)
+	if g.constant != 0 {
+		allMods = append(allMods, addConst{value: g.constant})
+	}
+	var total mod = summ{}
+	if g.success != nil {
+		total = *g.success
+	}
+	switch g.diceType {
+	case diceTypeNormal:
+		allMods = append(allMods, total)
+	case diceTypeConcat:
+		if g.success != nil {
+			return fmt.Errorf("concat dice cannot count successes")
+		}
+		// The digits are joined, not summed, so nothing applies to the joined number:
+		// DMs for the dice are passed to Roll and adjust them positionally.
+		for _, m := range allMods {
+			if m.priority() > priorityConcat {
+				return fmt.Errorf("concat dice cannot apply %s to the joined digits, pass per-die DMs instead", m.String())
+			}
+		}
+		allMods = append(allMods, concat{faces: g.sides})
+	case diceTypeDestructive:
+		if g.count < 2 {
+			return fmt.Errorf("destructive dice need at least 2 dice, got %d", g.count)
+		}
+		allMods = append(allMods, dropLowest{quantity: 1}, total)
+	default:
+		return fmt.Errorf("unknown dice type %s", g.diceType)
+	}
+	g.mods = sortModifiers(allMods)
+	for _, m := range g.mods {
+		if r, ok := m.(reroll); ok && !r.once && len(dice) > 0 && r.matchesEvery(dice[0]) {
+			return fmt.Errorf("reroll %s matches every face of %s", r.String(), g.String())
+		}
+	}
+	return nil
+}
+
+// String renders the group in canonical form: "1d6", "2d6+1", "3d6!>5:dl1", "(3d6+2):/2",
+// "6d6>=5", "4dF".
+func (g *diceGroup) String() string {
+	sb := strings.Builder{}
+	switch {
+	case g.custom == fudgeDie:
+		sb.WriteString(fmt.Sprintf("%d%s%s", g.count, g.diceType, fudgeDie))
+	case g.custom != "":
+		sb.WriteString(fmt.Sprintf("%d%s%s%s%s", g.count, g.diceType, customDieOpen, g.custom, customDieClose))
+	default:
+		sb.WriteString(fmt.Sprintf("%d%s%d", g.count, g.diceType, g.sides))
+	}
+	switch g.explode.mode {
+	case explodeAdd:
+		sb.WriteString(explodeSuffix)
+	case explodeCompound:
+		sb.WriteString(compoundSuffix)
+	}
+	if g.explode.mode != explodeNone && g.explode.threshold != g.sides {
+		sb.WriteString(fmt.Sprintf("%s%d", thresholdPrefix, g.explode.threshold))
+	}
+	if g.success != nil {
+		sb.WriteString(fmt.Sprintf("%s%d", g.success.cmp, g.success.target))
+	}
+	base := sb.String()
+	if g.constant != 0 {
+		if len(g.chain) == 0 {
+			return fmt.Sprintf("%s%+d", base, g.constant)
+		}
+		base = fmt.Sprintf("(%s%+d)", base, g.constant)
+	}
+	chain := sortModifiers(slices.Clone(g.chain))
+	for _, m := range chain {
+		base += complexModsSeparator + m.String()
+	}
+	return base
+}
@@ -0,0 +1,141 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxDice is the number of dice a single roll may draw unless the Manager
+	// is given another Budget.
+	DefaultMaxDice = 10000
+	// DefaultMaxRerolls is the number of rerolls a single roll may draw unless the
+	// Manager is given another Budget.
+	DefaultMaxRerolls = 10000
+)
+
+var (
+	// ErrBudgetExceeded fails a roll that would draw more dice or rerolls than its
+	// Budget allows.
+	ErrBudgetExceeded = errors.New("dice budget exceeded")
+	// ErrInvalidExpression fails an expression that does not parse. The parser error
+	// is wrapped as well.
+	ErrInvalidExpression = errors.New("invalid dice expression")
+)
+
+// Budget bounds the work of a single roll, so an expression typed in by a user, such
+// as "100000d1000" or a long exploding chain, cannot hang a server or a CLI.
+//
+// MaxDice counts the dice of the pools and the extra dice of explosions, MaxRerolls
+// the dice drawn by reroll modifiers. A limit of 0 or less is no limit. A pool that
+// is larger than MaxDice fails before a single die is drawn; the other limits fail
+// the roll as soon as they are hit.
+type Budget struct {
+	MaxDice    int
+	MaxRerolls int
+}
+
+// DefaultBudget returns the Budget of a Manager created without WithBudget.
+func DefaultBudget() Budget {
+	return Budget{MaxDice: DefaultMaxDice, MaxRerolls: DefaultMaxRerolls}
+}
+
+// WithBudget bounds every roll of the Manager and of the Managers derived from it.
+// Budget{} lifts the limits.
+func WithBudget(b Budget) Option {
+	return func(m *Manager) error {
+		m.budget = b
+		return nil
+	}
+}
+
+// Budget returns the Budget that bounds every roll of the Manager.
+func (m *Manager) Budget() Budget {
+	return m.budget
+}
+
+type budgetKey struct{}
+
+// ContextWithBudget returns a context that makes RollContext use b instead of the
+// Manager's Budget, e.g. a tighter limit for one request of a server.
+func ContextWithBudget(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// RollContext is like Roll but stops drawing dice as soon as ctx is done and bounds
+// the roll with the Budget of ctx (see ContextWithBudget) or of the Manager.
+// The error wraps ctx.Err(), ErrBudgetExceeded or ErrInvalidExpression.
+func (m *Manager) RollContext(ctx context.Context, expr string, mods ...int) (int, error) {
+	intrpr, _, err := m.evaluateContext(ctx, expr, nil, mods)
+	if err != nil {
+		return 0, fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
+	}
+	return intrpr.sum, nil
+}
+
+// RollContext uses the default manager to evaluate an expression under ctx.
+func RollContext(ctx context.Context, expr string, mods ...int) (int, error) {
+	return defaultManager.RollContext(ctx, expr, mods...)
+}
+
+// budgetFor returns the Budget of a roll under ctx.
+func (m *Manager) budgetFor(ctx context.Context) Budget {
+	if b, ok := ctx.Value(budgetKey{}).(Budget); ok {
+		return b
+	}
+	return m.budget
+}
+
+// checkPool fails an expression whose pools alone hold more dice than the budget allows.
+func (b Budget) checkPool(exp *expression) error {
+	if b.MaxDice <= 0 {
+		return nil
+	}
+	n := 0
+	for _, g := range exp.groups {
+		n += len(g.dicepool.dice)
+	}
+	if n > b.MaxDice {
+		return fmt.Errorf("%w: %d dice, the limit is %d", ErrBudgetExceeded, n, b.MaxDice)
+	}
+	return nil
+}
+
+// budgetRoller counts the draws of a single roll and stops drawing once the context
+// is done or the budget is spent. From then on it returns face 1 without drawing, so
+// the roll winds down quickly, and err reports why. It belongs to one roll and needs
+// no lock.
+type budgetRoller struct {
+	roller
+	ctx       context.Context
+	budget    Budget
+	dice      int
+	rerolls   int
+	rerolling bool
+	err       error
+}
+
+func (r *budgetRoller) roll(d die) int {
+	if r.err != nil {
+		return 1
+	}
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		return 1
+	}
+	if r.rerolling {
+		r.rerolls++
+		if r.budget.MaxRerolls > 0 && r.rerolls > r.budget.MaxRerolls {
+			r.err = fmt.Errorf("%w: more than %d rerolls", ErrBudgetExceeded, r.budget.MaxRerolls)
+			return 1
+		}
+	} else {
+		r.dice++
+		if r.budget.MaxDice > 0 && r.dice > r.budget.MaxDice {
+			r.err = fmt.Errorf("%w: more than %d dice", ErrBudgetExceeded, r.budget.MaxDice)
+			return 1
+		}
+	}
+	return r.roller.roll(d)
+}
@@ -1,33 +1,193 @@
 package dice
 
-import "sync"
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+)
 
-var exprCache = &expressionCache{}
+// DefaultCacheSize is the capacity of the package-wide expression cache.
+const DefaultCacheSize = 1024
 
-type expressionCache struct {
-	mu    sync.RWMutex
-	cache map[string]*expression
+var exprCache = NewCache(DefaultCacheSize)
+
+// Cache is a bounded least-recently-used cache of parsed expressions. Managers share
+// the package-wide cache (see DefaultCache) unless they are given their own with
+// WithCache, so expressions typed in by users cannot grow memory without bound.
+// It is safe for concurrent use; the lock is held only for the map lookup and the
+// recency update, never while parsing.
+type Cache struct {
+	mu        sync.Mutex
+	size      int
+	entries   map[string]*list.Element
+	order     *list.List // front is the most recently used
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// CacheStats reports the use of a Cache. Hits and Misses count lookups by the
+// Managers; Warm counts neither.
+type CacheStats struct {
+	Size      int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type cacheEntry struct {
+	key string
+	exp *expression
+}
+
+// NewCache creates a cache holding at most size entries; a size below 1 is
+// treated as 1. An expression takes up to two entries, its spelling and its
+// canonical form.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// DefaultCache returns the package-wide cache used by the package-level functions
+// and by every Manager created without WithCache.
+func DefaultCache() *Cache {
+	return exprCache
+}
+
+// WithCache makes the Manager parse through c instead of the package-wide cache.
+// Managers derived from it share c.
+func WithCache(c *Cache) Option {
+	return func(m *Manager) error {
+		if c == nil {
+			return fmt.Errorf("nil cache provided")
+		}
+		m.cache = c
+		return nil
+	}
+}
+
+// Warm parses the expressions and caches them ahead of the first roll, e.g. every
+// expression of a tables.Collection. The valid expressions are cached even when
+// others fail; the error lists every expression that did not parse.
+func (c *Cache) Warm(exprs ...string) error {
+	var errs []error
+	for _, expr := range exprs {
+		if _, err := c.lookup(expr, false); err != nil {
+			errs = append(errs, fmt.Errorf("expression %q: %w", expr, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func (ec *expressionCache) get(expr string) (*expression, bool) {
-	ec.mu.RLock()
-	defer ec.mu.RUnlock()
-	exp, ok := ec.cache[expr]
-	return exp, ok
+// Stats returns the current counters of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Size:      c.order.Len(),
+		Capacity:  c.size,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
 }
 
-func (ec *expressionCache) set(expr string, exp *expression) {
-	ec.mu.Lock()
-	defer ec.mu.Unlock()
-	ec.cache[expr] = exp
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
 }
 
-func (ec *expressionCache) clear() {
-	ec.mu.Lock()
-	defer ec.mu.Unlock()
-	ec.cache = make(map[string]*expression)
+// Resize changes the capacity of the cache, evicting the least recently used
+// entries that no longer fit. A size below 1 is treated as 1.
+func (c *Cache) Resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = max(size, 1)
+	c.evict()
 }
 
-func init() {
-	exprCache.cache = make(map[string]*expression)
-}
\ No newline at end of file
+// Purge drops every entry. The counters are kept.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+func (c *Cache) get(expr string, count bool) (*expression, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[expr]
+	if count {
+		if ok {
+			c.hits++
+		} else {
+			c.misses++
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).exp, true
+}
+
+// share stores exp under its canonical form and under expr, reusing the tree already
+// cached for the canonical form, and returns the tree to use.
+func (c *Cache) share(expr string, exp *expression) *expression {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[exp.canonical]; ok {
+		c.order.MoveToFront(el)
+		exp = el.Value.(*cacheEntry).exp
+	} else {
+		c.put(exp.canonical, exp)
+	}
+	c.put(expr, exp)
+	c.evict()
+	return exp
+}
+
+func (c *Cache) put(key string, exp *expression) {
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).exp = exp
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, exp: exp})
+}
+
+func (c *Cache) evict() {
+	for c.order.Len() > c.size {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// lookup returns the parsed expression from the cache, parsing and caching it on a miss.
+// Expressions are cached under their canonical form as well, so spellings of the same
+// expression ("2d6 + 1", "2d6+1") share one parsed tree. Parse errors are not cached.
+func (c *Cache) lookup(expr string, count bool) (*expression, error) {
+	if exp, ok := c.get(expr, count); ok {
+		return exp, nil
+	}
+	exp, err := newExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return c.share(expr, exp), nil
+}
+
+// lookupExpression looks the expression up in the package-wide cache.
+func lookupExpression(expr string) (*expression, error) {
+	return exprCache.lookup(expr, true)
+}
@@ -0,0 +1,188 @@
+package dice
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DefaultTarget is the target number of a Cepheus Engine task check.
+const DefaultTarget = 8
+
+const (
+	checkNormal = "2d6"
+	checkBoon   = "3DD6"
+	checkBane   = "3d6:dh1"
+)
+
+// Task describes a single 2D check.
+// Target 0 means DefaultTarget. Boon rolls 3D and keeps the best two, Bane keeps the
+// worst two; a task with both rolls normally.
+type Task struct {
+	DM     int
+	Target int
+	Boon   bool
+	Bane   bool
+}
+
+// CheckResult is the outcome of a task check.
+// Natural is the sum of the kept dice before the DM, Total includes the DM and
+// Effect is Total minus Target. A natural 2 always fails and a natural 12 always
+// succeeds, whatever the DM.
+type CheckResult struct {
+	Dice          []int
+	Kept          []int
+	Natural       int
+	DM            int
+	Total         int
+	Target        int
+	Effect        int
+	Success       bool
+	NaturalTwo    bool
+	NaturalTwelve bool
+}
+
+// String renders the result, e.g. "2D [3 5] +1 = 9 vs 8: success (Effect +1)".
+func (cr CheckResult) String() string {
+	outcome := "failure"
+	if cr.Success {
+		outcome = "success"
+	}
+	dice := fmt.Sprintf("2D %v", cr.Kept)
+	if len(cr.Dice) != len(cr.Kept) {
+		dice = fmt.Sprintf("%dD %v keep %v", len(cr.Dice), cr.Dice, cr.Kept)
+	}
+	return fmt.Sprintf("%s %+d = %d vs %d: %s (Effect %+d)", dice, cr.DM, cr.Total, cr.Target, outcome, cr.Effect)
+}
+
+// Check rolls a task check.
+func (m *Manager) Check(t Task) (CheckResult, error) {
+	return m.check(t)
+}
+
+// Check uses the default manager to roll a task check.
+func Check(t Task) (CheckResult, error) {
+	return defaultManager.Check(t)
+}
+
+func (m *Manager) check(t Task) (CheckResult, error) {
+	target := t.Target
+	if target == 0 {
+		target = DefaultTarget
+	}
+	expr := checkNormal
+	switch {
+	case t.Boon && !t.Bane:
+		expr = checkBoon
+	case t.Bane && !t.Boon:
+		expr = checkBane
+	}
+	intrpr, res, err := m.evaluate(expr, nil, []int{t.DM})
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("check %+v failed: %w", t, err)
+	}
+	rolled := slices.Clone(res.raw)
+	kept := slices.Clone(rolled)
+	if len(kept) > 2 {
+		slices.Sort(kept)
+		if expr == checkBoon {
+			kept = kept[len(kept)-2:]
+		} else {
+			kept = kept[:2]
+		}
+	}
+	cr := CheckResult{
+		Dice:    rolled,
+		Kept:    kept,
+		Natural: intrpr.sum - t.DM,
+		DM:      t.DM,
+		Total:   intrpr.sum,
+		Target:  target,
+		Effect:  intrpr.sum - target,
+	}
+	cr.NaturalTwo = cr.Natural == 2
+	cr.NaturalTwelve = cr.Natural == 12
+	cr.Success = cr.Effect >= 0
+	switch {
+	case cr.NaturalTwo:
+		cr.Success = false
+	case cr.NaturalTwelve:
+		cr.Success = true
+	}
+	return cr, nil
+}
+
+// OpposedResult is the outcome of an opposed check. Winner is 1 when the active side
+// has the higher Effect, -1 when the opposing side has, and 0 on a tie. Margin is the
+// difference between the two Effects.
+type OpposedResult struct {
+	Active   CheckResult
+	Opposing CheckResult
+	Winner   int
+	Margin   int
+}
+
+// Opposed rolls a check for both sides; the higher Effect wins.
+func (m *Manager) Opposed(active, opposing Task) (OpposedResult, error) {
+	a, err := m.check(active)
+	if err != nil {
+		return OpposedResult{}, fmt.Errorf("active side: %w", err)
+	}
+	o, err := m.check(opposing)
+	if err != nil {
+		return OpposedResult{}, fmt.Errorf("opposing side: %w", err)
+	}
+	res := OpposedResult{Active: a, Opposing: o, Margin: a.Effect - o.Effect}
+	switch {
+	case res.Margin > 0:
+		res.Winner = 1
+	case res.Margin < 0:
+		res.Winner = -1
+	}
+	return res, nil
+}
+
+// Chain rolls a task chain: the Effect of every check is added to the DM of the next.
+func (m *Manager) Chain(tasks ...Task) ([]CheckResult, error) {
+	return m.ChainWith(nil, tasks...)
+}
+
+// ChainWith rolls a task chain, converting the Effect of every check into the DM of
+// the next with dm. A nil dm passes the Effect on unchanged; TaskChainDM implements
+// the graded Cepheus Engine table.
+func (m *Manager) ChainWith(dm func(effect int) int, tasks ...Task) ([]CheckResult, error) {
+	if dm == nil {
+		dm = func(effect int) int { return effect }
+	}
+	results := make([]CheckResult, 0, len(tasks))
+	carry := 0
+	for i, t := range tasks {
+		t.DM += carry
+		cr, err := m.check(t)
+		if err != nil {
+			return results, fmt.Errorf("task %d of chain: %w", i+1, err)
+		}
+		results = append(results, cr)
+		carry = dm(cr.Effect)
+	}
+	return results, nil
+}
+
+// TaskChainDM converts the Effect of a check into the DM for the next task of a chain:
+// -6 or less gives -3, -5 to -2 gives -2, -1 gives -1, 0 gives +1, 1 to 5 gives +2 and
+// 6 or more gives +3.
+func TaskChainDM(effect int) int {
+	switch {
+	case effect <= -6:
+		return -3
+	case effect <= -2:
+		return -2
+	case effect == -1:
+		return -1
+	case effect == 0:
+		return 1
+	case effect <= 5:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,159 @@
+package dice
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// DefaultVarianceResolution is the number of steps of Variance: 0.000 to 1.000.
+	DefaultVarianceResolution = 1000
+	// continuousResolution is the number of steps behind Uniform and the distributions.
+	continuousResolution = 1 << 30
+)
+
+// VarianceResolution returns a random float64 in [0.0, 1.0] in steps of 1/resolution,
+// all resolution+1 values equally likely. It draws from the Manager's stream, so it is
+// as reproducible as any roll and recorded for replay, but it is not a dice roll and
+// is not logged. A resolution below 1 uses DefaultVarianceResolution.
+func (m *Manager) VarianceResolution(resolution int) float64 {
+	if resolution < 1 {
+		resolution = DefaultVarianceResolution
+	}
+	r := m.draw(resolution+1) - 1
+	return float64(r) / float64(resolution)
+}
+
+// VarianceResolution uses the default manager.
+func VarianceResolution(resolution int) float64 {
+	return defaultManager.VarianceResolution(resolution)
+}
+
+// Uniform returns a random float64 in [lo, hi), e.g. jitter of an orbit in AU.
+// The bounds may be given in either order.
+func (m *Manager) Uniform(lo, hi float64) float64 {
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	return lo + (hi-lo)*m.unit()
+}
+
+// Uniform uses the default manager.
+func Uniform(lo, hi float64) float64 {
+	return defaultManager.Uniform(lo, hi)
+}
+
+// Normal returns a normally distributed float64 with the given mean and standard
+// deviation (Box-Muller transform, two draws per value).
+func (m *Manager) Normal(mean, stddev float64) float64 {
+	u1, u2 := m.unit(), m.unit()
+	z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+	return mean + stddev*z
+}
+
+// Normal uses the default manager.
+func Normal(mean, stddev float64) float64 {
+	return defaultManager.Normal(mean, stddev)
+}
+
+// LogNormal returns exp(Normal(mu, sigma)): a positive value skewed to the right,
+// such as an eccentricity or a mass factor. mu and sigma describe the logarithm.
+func (m *Manager) LogNormal(mu, sigma float64) float64 {
+	return math.Exp(m.Normal(mu, sigma))
+}
+
+// LogNormal uses the default manager.
+func LogNormal(mu, sigma float64) float64 {
+	return defaultManager.LogNormal(mu, sigma)
+}
+
+// Triangular returns a float64 in [lo, hi] from the triangular distribution peaking
+// at mode. The bounds may be given in either order; mode is clamped between them.
+func (m *Manager) Triangular(lo, mode, hi float64) float64 {
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	mode = min(max(mode, lo), hi)
+	u := m.unit()
+	if hi == lo {
+		return lo
+	}
+	if u < (mode-lo)/(hi-lo) {
+		return lo + math.Sqrt(u*(hi-lo)*(mode-lo))
+	}
+	return hi - math.Sqrt((1-u)*(hi-lo)*(hi-mode))
+}
+
+// Triangular uses the default manager.
+func Triangular(lo, mode, hi float64) float64 {
+	return defaultManager.Triangular(lo, mode, hi)
+}
+
+// unit returns a float64 in the open interval (0, 1): the middle of one of
+// continuousResolution equal steps, so it is never 0 and Normal can take its log.
+// Like VarianceResolution it draws from the stream without logging a roll.
+func (m *Manager) unit() float64 {
+	r := m.draw(continuousResolution)
+	return (float64(r) - 0.5) / continuousResolution
+}
+
+// FluxDice rolls count dice with the given number of sides and returns the sum of the
+// first half minus the sum of the second half, plus modifiers: FluxDice(2, 10) is a
+// d10 Flux, FluxDice(4, 6) is 2D6 minus 2D6. count must be even and positive.
+func (m *Manager) FluxDice(count, sides int, dm ...int) (int, error) {
+	raw, err := m.fluxRoll(count, sides)
+	if err != nil {
+		return 0, err
+	}
+	return sum(raw[:count/2]...) - sum(raw[count/2:]...) + sum(dm...), nil
+}
+
+// FluxDice uses the default manager.
+func FluxDice(count, sides int, dm ...int) (int, error) {
+	return defaultManager.FluxDice(count, sides, dm...)
+}
+
+// FluxGoodDice is like FluxDice but subtracts the lower half of the dice from the
+// higher half, so the result before modifiers is never negative.
+func (m *Manager) FluxGoodDice(count, sides int, dm ...int) (int, error) {
+	raw, err := m.fluxRoll(count, sides)
+	if err != nil {
+		return 0, err
+	}
+	slices.Sort(raw)
+	return sum(raw[count/2:]...) - sum(raw[:count/2]...) + sum(dm...), nil
+}
+
+// FluxGoodDice uses the default manager.
+func FluxGoodDice(count, sides int, dm ...int) (int, error) {
+	return defaultManager.FluxGoodDice(count, sides, dm...)
+}
+
+// FluxBadDice is like FluxDice but subtracts the higher half of the dice from the
+// lower half, so the result before modifiers is never positive.
+func (m *Manager) FluxBadDice(count, sides int, dm ...int) (int, error) {
+	raw, err := m.fluxRoll(count, sides)
+	if err != nil {
+		return 0, err
+	}
+	slices.Sort(raw)
+	return sum(raw[:count/2]...) - sum(raw[count/2:]...) + sum(dm...), nil
+}
+
+// FluxBadDice uses the default manager.
+func FluxBadDice(count, sides int, dm ...int) (int, error) {
+	return defaultManager.FluxBadDice(count, sides, dm...)
+}
+
+// fluxRoll rolls the dice of a Flux in a single roll and returns their values.
+func (m *Manager) fluxRoll(count, sides int) ([]int, error) {
+	if count < 2 || count%2 != 0 {
+		return nil, fmt.Errorf("flux needs an even number of dice, got %d", count)
+	}
+	_, res, err := m.RollResult(fmt.Sprintf("%dd%d", count, sides))
+	if err != nil {
+		return nil, err
+	}
+	return res.Raw(), nil
+}
@@ -0,0 +1,67 @@
+package dice
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+)
+
+const (
+	// deriveSeparator joins the parent seed identity and the label of a derived Manager.
+	deriveSeparator = "/"
+	// shardLabel prefixes the label of the Managers returned by Shard.
+	shardLabel = "shard:"
+)
+
+// Derive returns a child Manager with its own independent random stream.
+//
+// The child stream depends only on the parent's seed identity (see Seed) and label,
+// never on how many dice the parent already rolled, so Derive("0304") always yields
+// the same stream and rolling on it does not disturb Derive("0305"). Derivation can be
+// chained: m.Derive("0304").Derive("step2").
+//
+// The derivation is part of the package contract and is kept stable across releases:
+// the child identity is "<parent identity>/<label>", hashed with SHA-256, and the first
+// 16 bytes of the digest (two little-endian uint64) seed a math/rand/v2 PCG generator.
+// Children of a crypto source use crypto/rand as well and are not reproducible.
+// The child inherits the parent's logger, expression cache and budget.
+func (m *Manager) Derive(label string) *Manager {
+	id := m.Seed() + deriveSeparator + label
+	var r roller
+	if isCryptoRoller(m.roller) {
+		r = &sourceRoller{src: NewCryptoSource()}
+	} else {
+		r = &sourceRoller{src: newDerivedSource(id)}
+	}
+	child := newManager(r)
+	child.logger.Store(m.logger.Load())
+	child.cache = m.cache
+	child.budget = m.budget
+	return child
+}
+
+// Shard returns the i-th shard of the Manager, a derived Manager with the label
+// "shard:<i>". Shards give parallel workers their own streams without contention:
+// worker i rolling on m.Shard(i) gets the same dice on every run, however the
+// goroutines are scheduled.
+func (m *Manager) Shard(i int) *Manager {
+	return m.Derive(fmt.Sprintf("%s%d", shardLabel, i))
+}
+
+// newDerivedSource creates the PCG source for a derived identity.
+func newDerivedSource(id string) Source {
+	digest := sha256.Sum256([]byte(id))
+	seed1 := binary.LittleEndian.Uint64(digest[0:8])
+	seed2 := binary.LittleEndian.Uint64(digest[8:16])
+	return newStateSource(sourcePCG, id, rand.NewPCG(seed1, seed2))
+}
+
+func isCryptoRoller(r roller) bool {
+	sr, ok := r.(*sourceRoller)
+	if !ok {
+		return false
+	}
+	rs, ok := sr.src.(*randSource)
+	return ok && rs.kind == sourceCrypto
+}
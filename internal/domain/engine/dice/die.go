@@ -1,10 +1,29 @@
 package dice
 
+const (
+	explodeNone = iota
+	explodeAdd
+	explodeCompound
+)
+
+// maxExplosionChain caps the number of extra rolls a single exploding die may produce.
+const maxExplosionChain = 100
+
+// explosion describes how a die behaves when it rolls at or above its threshold.
+type explosion struct {
+	mode      int
+	threshold int
+}
+
 // die represents a single physical die with a number of faces and optional metadata.
+// values maps faces (1-based) to the value they count as; without it a face counts
+// as its number. codes holds the symbol shown on each face, if any.
 type die struct {
 	faces    int
+	values   []int
 	codes    map[int]string
 	metadata map[string]string
+	explode  explosion
 }
 
 func newDie(faces int) die {
@@ -16,11 +35,39 @@ func (d die) withCodes(codes map[int]string) die {
 	return d
 }
 
+func (d die) withValues(values []int) die {
+	d.values = values
+	return d
+}
+
+// value returns what the rolled face counts as.
+func (d die) value(face int) int {
+	if d.values == nil || face < 1 || face > len(d.values) {
+		return face
+	}
+	return d.values[face-1]
+}
+
+// code returns the symbol of the rolled face, or "" for plain dice.
+func (d die) code(face int) string {
+	return d.codes[face]
+}
+
 func (d die) withMeta(meta map[string]string) die {
 	d.metadata = meta
 	return d
 }
 
+func (d die) withExplosion(ex explosion) die {
+	d.explode = ex
+	return d
+}
+
+// explodes reports whether the rolled value triggers another roll.
+func (d die) explodes(value int) bool {
+	return d.explode.mode != explodeNone && value >= d.explode.threshold
+}
+
 // dicepool is a collection of dice that are rolled together.
 type dicepool struct {
 	dice     []die
@@ -37,4 +84,4 @@ func newDicepool(dice ...die) dicepool {
 func (dp dicepool) withMeta(meta map[string]string) dicepool {
 	dp.metadata = meta
 	return dp
-}
\ No newline at end of file
+}
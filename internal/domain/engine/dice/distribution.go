@@ -0,0 +1,596 @@
+package dice
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// maxDistributionOutcomes caps the number of dice combinations enumerated when an
+// expression cannot be solved by convolution (drop modifiers, positional adds with drops).
+const maxDistributionOutcomes = 1 << 22
+
+// ProbabilityDistribution is the exact probability mass function of a dice expression.
+type ProbabilityDistribution struct {
+	Min      int
+	Max      int
+	Mean     float64
+	Variance float64
+	StdDev   float64
+	Table    map[int]float64
+}
+
+// Distribution computes the exact probability distribution of the expression with all
+// modifiers applied. Exploding dice are computed up to the same chain cap used when rolling.
+func Distribution(expr string) (ProbabilityDistribution, error) {
+	return DistributionWith(expr, nil)
+}
+
+// DistributionWith is like Distribution but resolves the variables of the expression from vars.
+func DistributionWith(expr string, vars Resolver) (ProbabilityDistribution, error) {
+	exp, err := lookupExpression(expr)
+	if err != nil {
+		return ProbabilityDistribution{}, err
+	}
+	pmf, err := expressionPMF(exp, vars)
+	if err != nil {
+		return ProbabilityDistribution{}, fmt.Errorf("distribution of %q failed: %w", expr, err)
+	}
+	return newProbabilityDistribution(pmf), nil
+}
+
+func newProbabilityDistribution(pmf map[int]float64) ProbabilityDistribution {
+	pd := ProbabilityDistribution{Table: pmf}
+	first := true
+	for v, p := range pmf {
+		if first || v < pd.Min {
+			pd.Min = v
+		}
+		if first || v > pd.Max {
+			pd.Max = v
+		}
+		first = false
+		pd.Mean += float64(v) * p
+	}
+	for v, p := range pmf {
+		d := float64(v) - pd.Mean
+		pd.Variance += d * d * p
+	}
+	pd.StdDev = math.Sqrt(pd.Variance)
+	return pd
+}
+
+// Outcomes returns every possible result in ascending order.
+func (pd ProbabilityDistribution) Outcomes() []int {
+	out := make([]int, 0, len(pd.Table))
+	for v := range pd.Table {
+		out = append(out, v)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// P returns the probability of rolling exactly n.
+func (pd ProbabilityDistribution) P(n int) float64 {
+	return pd.Table[n]
+}
+
+// AtLeast returns the probability of rolling n or higher.
+func (pd ProbabilityDistribution) AtLeast(n int) float64 {
+	p := 0.0
+	for v, pv := range pd.Table {
+		if v >= n {
+			p += pv
+		}
+	}
+	return p
+}
+
+// AtMost returns the probability of rolling n or lower.
+func (pd ProbabilityDistribution) AtMost(n int) float64 {
+	p := 0.0
+	for v, pv := range pd.Table {
+		if v <= n {
+			p += pv
+		}
+	}
+	return p
+}
+
+// Percentile returns the smallest result whose cumulative probability reaches p (0.0–1.0).
+func (pd ProbabilityDistribution) Percentile(p float64) int {
+	outcomes := pd.Outcomes()
+	if len(outcomes) == 0 {
+		return 0
+	}
+	cumulative := 0.0
+	for _, v := range outcomes {
+		cumulative += pd.Table[v]
+		if cumulative >= p-1e-12 {
+			return v
+		}
+	}
+	return outcomes[len(outcomes)-1]
+}
+
+// expressionPMF computes the distribution of every dice group and combines them along
+// the expression tree. Dice groups are independent, so binary operations and functions
+// combine the distributions of their operands by cross product.
+func expressionPMF(exp *expression, vars Resolver) (map[int]float64, error) {
+	return nodePMF(exp.root, &env{vars: vars})
+}
+
+func nodePMF(n node, e *env) (map[int]float64, error) {
+	switch nn := n.(type) {
+	case numberNode:
+		return map[int]float64{nn.value: 1}, nil
+	case varNode:
+		v, err := e.lookup(nn.name)
+		if err != nil {
+			return nil, err
+		}
+		return map[int]float64{v: 1}, nil
+	case diceNode:
+		return groupPMF(nn.group)
+	case negNode:
+		pmf, err := nodePMF(nn.operand, e)
+		if err != nil {
+			return nil, err
+		}
+		return combinePMF(pmf, map[int]float64{0: 1}, func(v, _ int) (int, error) { return -v, nil })
+	case binaryNode:
+		left, right, err := operandPMFs(nn.left, nn.right, e)
+		if err != nil {
+			return nil, err
+		}
+		return combinePMF(left, right, func(l, r int) (int, error) { return applyOperator(nn.op, l, r) })
+	case funcNode:
+		if div, ok := nn.roundedDivision(); ok {
+			left, right, err := operandPMFs(div.left, div.right, e)
+			if err != nil {
+				return nil, err
+			}
+			return combinePMF(left, right, func(l, r int) (int, error) { return roundDivision(nn.name, l, r) })
+		}
+		pmf, err := nodePMF(nn.args[0], e)
+		if err != nil {
+			return nil, err
+		}
+		if len(nn.args) == 1 {
+			return combinePMF(pmf, map[int]float64{0: 1}, func(v, _ int) (int, error) {
+				return applyFunction(nn.name, []int{v})
+			})
+		}
+		for _, arg := range nn.args[1:] {
+			next, err := nodePMF(arg, e)
+			if err != nil {
+				return nil, err
+			}
+			pmf, err = combinePMF(pmf, next, func(acc, v int) (int, error) {
+				return applyFunction(nn.name, []int{acc, v})
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pmf, nil
+	default:
+		return nil, fmt.Errorf("unsupported expression node %s", n.String())
+	}
+}
+
+func operandPMFs(left, right node, e *env) (map[int]float64, map[int]float64, error) {
+	l, err := nodePMF(left, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := nodePMF(right, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+// combinePMF returns the distribution of f over two independent distributions.
+func combinePMF(a, b map[int]float64, f func(int, int) (int, error)) (map[int]float64, error) {
+	out := make(map[int]float64)
+	for x, px := range a {
+		for y, py := range b {
+			v, err := f(x, y)
+			if err != nil {
+				return nil, err
+			}
+			out[v] += px * py
+		}
+	}
+	return out, nil
+}
+
+// groupPMF splits the mods of a dice group into the per-die stage, the aggregation (sum
+// or concat) and the scalar stage, and solves the per-die stage either by convolution
+// or enumeration.
+func groupPMF(g *diceGroup) (map[int]float64, error) {
+	var pre, post []mod
+	var agg mod
+	for _, m := range g.mods {
+		switch {
+		case m.priority() < prioritySum:
+			pre = append(pre, m)
+		case agg == nil && m.priority() == prioritySum:
+			agg = m
+		default:
+			post = append(post, m)
+		}
+	}
+	if agg == nil {
+		return nil, fmt.Errorf("expression has no aggregation step")
+	}
+
+	var pmf map[int]float64
+	var err error
+	if independentMods(pre) {
+		pmf, err = convolvedPMF(g.dicepool.dice, pre, agg)
+	} else {
+		pmf, err = enumeratedPMF(g.dicepool.dice, pre, agg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapPMF(pmf, post)
+}
+
+// independentMods reports whether every per-die mod affects dice one at a time,
+// so the dice stay independent and the pool can be convolved.
+func independentMods(mods []mod) bool {
+	for _, m := range mods {
+		switch m.(type) {
+		case addToEach, addIndividual, reroll, clampMin, clampMax:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// convolvedPMF handles pools where each die contributes independently of the others.
+func convolvedPMF(dice []die, pre []mod, agg mod) (map[int]float64, error) {
+	eachAdd := 0
+	individual := map[int]int{}
+	var rerolls, clamps []mod
+	for _, m := range pre {
+		switch mm := m.(type) {
+		case reroll:
+			rerolls = append(rerolls, mm)
+		case clampMin, clampMax:
+			clamps = append(clamps, mm)
+		case addToEach:
+			eachAdd += mm.value
+		case addIndividual:
+			if mm.position < 1 || mm.position > len(dice) {
+				return nil, fmt.Errorf("position %d out of range (1..%d)", mm.position, len(dice))
+			}
+			individual[mm.position-1] += mm.value
+		}
+	}
+	cc, isConcat := agg.(concat)
+	cs, isCount := agg.(countSuccesses)
+	for _, d := range dice {
+		if d.explode.mode == explodeAdd && (isConcat || len(individual) > 0) {
+			return nil, fmt.Errorf("exploding dice change pool positions and cannot be analysed with positional modifiers")
+		}
+		if d.explode.mode == explodeAdd && isCount {
+			return nil, fmt.Errorf("exploding dice change pool size and cannot be analysed as a success pool")
+		}
+		if d.explode.mode == explodeAdd && len(rerolls) > 0 {
+			return nil, fmt.Errorf("exploding dice change pool positions and cannot be analysed with rerolls")
+		}
+	}
+
+	pmf := map[int]float64{0: 1}
+	for i, d := range dice {
+		var dd map[int]float64
+		switch d.explode.mode {
+		case explodeAdd:
+			// Every roll of the chain is a die of its own in the pool, clamped on its own.
+			dd = chainPMF(d, eachAdd, clamps, individual[i])
+		default:
+			base, err := rerollPMF(d, chainPMF(d, 0, nil, 0), rerolls)
+			if err != nil {
+				return nil, err
+			}
+			dd = make(map[int]float64, len(base))
+			for v, p := range base {
+				dd[clampRoll(v+eachAdd+individual[i], clamps)] += p
+			}
+		}
+		if isConcat {
+			scale := 1
+			for range (len(dice) - 1 - i) * cc.width() {
+				scale *= 10
+			}
+			scaled := map[int]float64{}
+			for v, p := range dd {
+				scaled[cc.digit(v)*scale] += p
+			}
+			dd = scaled
+		} else if isCount {
+			counted := map[int]float64{}
+			for v, p := range dd {
+				if cs.passes(v) {
+					counted[1] += p
+				} else {
+					counted[0] += p
+				}
+			}
+			dd = counted
+		} else if _, ok := agg.(summ); !ok {
+			return nil, fmt.Errorf("unsupported aggregation step")
+		}
+		pmf = convolve(pmf, dd)
+	}
+	return pmf, nil
+}
+
+// chainPMF returns the distribution of a single die including its explosion chain.
+// perRoll is added to every roll of the chain and clamps then limit it, once is added
+// to the total a single time. Explosions follow the face rolled, not the clamped value.
+func chainPMF(d die, perRoll int, clamps []mod, once int) map[int]float64 {
+	out := map[int]float64{}
+	face := 1 / float64(d.faces)
+	live := map[int]float64{0: 1}
+	for depth := 0; depth <= maxExplosionChain && len(live) > 0; depth++ {
+		next := map[int]float64{}
+		for total, p := range live {
+			for f := 1; f <= d.faces; f++ {
+				v := d.value(f)
+				t := total + clampRoll(v+perRoll, clamps)
+				if d.explodes(v) && depth < maxExplosionChain {
+					next[t] += p * face
+					continue
+				}
+				out[t+once] += p * face
+			}
+		}
+		live = next
+	}
+	return out
+}
+
+// clampRoll applies clamp mods to the value of a single roll.
+func clampRoll(v int, clamps []mod) int {
+	for _, m := range clamps {
+		switch c := m.(type) {
+		case clampMin:
+			v = max(v, c.value)
+		case clampMax:
+			v = min(v, c.value)
+		}
+	}
+	return v
+}
+
+// rerollPMF applies reroll mods to the distribution of a single die. A reroll once
+// keeps the new value whatever it is; an unlimited reroll conditions the die on not
+// matching. Rerolls draw a single face, so they start from the plain face distribution.
+func rerollPMF(d die, pmf map[int]float64, rerolls []mod) (map[int]float64, error) {
+	if len(rerolls) == 0 {
+		return pmf, nil
+	}
+	fresh := chainPMF(d.withExplosion(explosion{}), 0, nil, 0)
+	for _, m := range rerolls {
+		r := m.(reroll)
+		matched := 0.0
+		for v, p := range pmf {
+			if r.test.passes(v) {
+				matched += p
+			}
+		}
+		out := map[int]float64{}
+		if r.once {
+			for v, p := range pmf {
+				if !r.test.passes(v) {
+					out[v] += p
+				}
+			}
+			for v, p := range fresh {
+				out[v] += matched * p
+			}
+		} else {
+			kept := 0.0
+			for v, p := range fresh {
+				if !r.test.passes(v) {
+					kept += p
+				}
+			}
+			if kept == 0 {
+				return nil, fmt.Errorf("reroll %s matches every face", r.String())
+			}
+			for v, p := range pmf {
+				if !r.test.passes(v) {
+					out[v] += p
+				}
+			}
+			for v, p := range fresh {
+				if !r.test.passes(v) {
+					out[v] += matched * p / kept
+				}
+			}
+		}
+		pmf = out
+	}
+	return pmf, nil
+}
+
+func convolve(a, b map[int]float64) map[int]float64 {
+	out := make(map[int]float64, len(a)+len(b))
+	for va, pa := range a {
+		for vb, pb := range b {
+			out[va+vb] += pa * pb
+		}
+	}
+	return out
+}
+
+// enumeratedPMF walks every combination of dice values and runs the real mods on it.
+// Identical dice without positional mods are walked as multisets.
+func enumeratedPMF(dice []die, pre []mod, agg mod) (map[int]float64, error) {
+	var rerolls, stages []mod
+	for _, m := range pre {
+		if _, ok := m.(reroll); ok {
+			rerolls = append(rerolls, m)
+			continue
+		}
+		stages = append(stages, m)
+	}
+	stages = append(stages, agg)
+	supports := make([]map[int]float64, len(dice))
+	for i, d := range dice {
+		if d.explode.mode == explodeAdd {
+			return nil, fmt.Errorf("exploding dice change pool size and cannot be analysed with drop modifiers")
+		}
+		support, err := rerollPMF(d, chainPMF(d, 0, nil, 0), rerolls)
+		if err != nil {
+			return nil, err
+		}
+		supports[i] = support
+	}
+	pmf := map[int]float64{}
+	visit := func(values []int, p float64) error {
+		out, err := applyMods(values, stages)
+		if err != nil {
+			return err
+		}
+		pmf[sum(out...)] += p
+		return nil
+	}
+
+	if identicalDice(dice) && !positionalMods(pre, agg) {
+		return pmf, enumerateMultisets(supports[0], len(dice), visit)
+	}
+	combinations := 1
+	for _, s := range supports {
+		combinations *= len(s)
+		if combinations > maxDistributionOutcomes {
+			return nil, fmt.Errorf("too many dice combinations to enumerate (limit %d)", maxDistributionOutcomes)
+		}
+	}
+	return pmf, enumerateSequences(supports, visit)
+}
+
+func applyMods(values []int, mods []mod) ([]int, error) {
+	out := slices.Clone(values)
+	var err error
+	for _, m := range mods {
+		out, err = m.apply(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func identicalDice(dice []die) bool {
+	for _, d := range dice {
+		if d.faces != dice[0].faces || d.explode != dice[0].explode || !slices.Equal(d.values, dice[0].values) {
+			return false
+		}
+	}
+	return true
+}
+
+func positionalMods(pre []mod, agg mod) bool {
+	if _, ok := agg.(concat); ok {
+		return true
+	}
+	for _, m := range pre {
+		if _, ok := m.(addIndividual); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// enumerateSequences visits every ordered combination of dice values.
+func enumerateSequences(supports []map[int]float64, visit func([]int, float64) error) error {
+	values := make([]int, len(supports))
+	var walk func(i int, p float64) error
+	walk = func(i int, p float64) error {
+		if i == len(supports) {
+			return visit(values, p)
+		}
+		for v, pv := range supports[i] {
+			values[i] = v
+			if err := walk(i+1, p*pv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(0, 1)
+}
+
+// enumerateMultisets visits every unordered combination of n identical dice,
+// weighted by its multinomial probability.
+func enumerateMultisets(support map[int]float64, n int, visit func([]int, float64) error) error {
+	faces := make([]int, 0, len(support))
+	for v := range support {
+		faces = append(faces, v)
+	}
+	slices.Sort(faces)
+	if multisetCount(len(faces), n) > maxDistributionOutcomes {
+		return fmt.Errorf("too many dice combinations to enumerate (limit %d)", maxDistributionOutcomes)
+	}
+	values := make([]int, 0, n)
+	logFact := make([]float64, n+1)
+	for i := 1; i <= n; i++ {
+		logFact[i] = logFact[i-1] + math.Log(float64(i))
+	}
+	var walk func(start, left int, logP float64, run int) error
+	walk = func(start, left int, logP float64, run int) error {
+		if left == 0 {
+			return visit(values, math.Exp(logFact[n]+logP))
+		}
+		for fi := start; fi < len(faces); fi++ {
+			r := 1
+			if fi == start && len(values) > 0 && values[len(values)-1] == faces[fi] {
+				r = run + 1
+			}
+			values = append(values, faces[fi])
+			err := walk(fi, left-1, logP+math.Log(support[faces[fi]])-math.Log(float64(r)), r)
+			values = values[:len(values)-1]
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(0, n, 0, 0)
+}
+
+// multisetCount returns C(faces+n-1, n), saturating above the enumeration limit.
+func multisetCount(faces, n int) int {
+	c := 1
+	for i := 1; i <= n; i++ {
+		c = c * (faces + i - 1) / i
+		if c > maxDistributionOutcomes {
+			return c
+		}
+	}
+	return c
+}
+
+// mapPMF pushes every value of the distribution through the scalar mods.
+func mapPMF(pmf map[int]float64, mods []mod) (map[int]float64, error) {
+	if len(mods) == 0 {
+		return pmf, nil
+	}
+	out := make(map[int]float64, len(pmf))
+	for v, p := range pmf {
+		res, err := applyMods([]int{v}, mods)
+		if err != nil {
+			return nil, err
+		}
+		out[sum(res...)] += p
+	}
+	return out, nil
+}
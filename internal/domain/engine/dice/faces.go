@@ -0,0 +1,76 @@
+package dice
+
+import (
+	"fmt"
+	"sync"
+)
+
+// fudgeDie is the name of the built-in Fudge/FATE die, written "dF".
+const fudgeDie = "F"
+
+// Face is one face of a custom die: the symbol it shows and the value it counts as.
+type Face struct {
+	Code  string
+	Value int
+}
+
+var customDice = &dieRegistry{
+	faces: map[string][]Face{
+		fudgeDie: {{Code: "-", Value: -1}, {Code: "0", Value: 0}, {Code: "+", Value: 1}},
+	},
+}
+
+type dieRegistry struct {
+	mu    sync.RWMutex
+	faces map[string][]Face
+}
+
+func (dr *dieRegistry) get(name string) ([]Face, bool) {
+	dr.mu.RLock()
+	defer dr.mu.RUnlock()
+	faces, ok := dr.faces[name]
+	return faces, ok
+}
+
+// DefineDie registers a custom die under name so expressions can roll it as
+// "Nd[name]", e.g. DefineDie("Boost", Face{"", 0}, Face{"*", 1}, ...) and "2d[Boost]".
+// Every face is equally likely; the faces show up in Result.Codes. A name can be
+// defined only once, and "F" is the built-in Fudge die.
+func DefineDie(name string, faces ...Face) error {
+	if !validDieName(name) {
+		return fmt.Errorf("invalid die name %q", name)
+	}
+	if len(faces) == 0 {
+		return fmt.Errorf("die %q has no faces", name)
+	}
+	customDice.mu.Lock()
+	defer customDice.mu.Unlock()
+	if _, ok := customDice.faces[name]; ok {
+		return fmt.Errorf("die %q is already defined", name)
+	}
+	customDice.faces[name] = append([]Face(nil), faces...)
+	return nil
+}
+
+func validDieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) && name[i] != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// newCustomDie builds a die from a list of faces.
+func newCustomDie(faces []Face) die {
+	values := make([]int, len(faces))
+	codes := make(map[int]string, len(faces))
+	for i, f := range faces {
+		values[i] = f.Value
+		codes[i+1] = f.Code
+	}
+	return newDie(len(faces)).withValues(values).withCodes(codes)
+}
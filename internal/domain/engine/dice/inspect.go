@@ -0,0 +1,373 @@
+package dice
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DiceType tells how the dice of a group are combined.
+type DiceType string
+
+const (
+	// DiceSum dice ("3d6") are summed, or counted for success pools.
+	DiceSum DiceType = diceTypeNormal
+	// DiceConcat dice ("2D6") are read as digits and joined.
+	DiceConcat DiceType = diceTypeConcat
+	// DiceDestructive dice ("3DD6") drop the lowest die and sum the rest.
+	DiceDestructive DiceType = diceTypeDestructive
+)
+
+// Expression is the public parsed form of a dice expression, as returned by Inspect.
+// Min and Max are the theoretical range of the total before external DMs; for
+// exploding dice Max assumes every die explodes as often as the roller allows.
+type Expression struct {
+	Source    string
+	Canonical string
+	Groups    []DiceGroup
+	Variables []string
+	Min       int
+	Max       int
+}
+
+// DiceGroup is a single dice term of an expression, e.g. "4d6:dl1" in "4d6:dl1+@DM".
+// Modifiers lists every modifier in the order it is applied, including the implicit
+// ones that combine the dice (sum, concat, count successes).
+type DiceGroup struct {
+	Canonical string
+	Count     int
+	Faces     int
+	Type      DiceType
+	Die       string // custom die name, "F" for Fudge dice, "" for numbered dice
+	Explode   string // "!" adds a die, "!!" compounds, "" does not explode
+	ExplodeAt int    // lowest value that explodes, 0 when the dice do not explode
+	Modifiers []Modifier
+	Min       int
+	Max       int
+}
+
+// Modifier is one step of the modifier chain of a dice group.
+// Kind is the name also reported in Stage.Modifier ("drop lowest", "reroll once",
+// "add to sum", ...). Value is the amount, quantity or target of the modifier,
+// Position the 1-based die of "add individual" and Compare the comparison of
+// rerolls and success counts. Text is the modifier in expression syntax, "" for the
+// implicit ones.
+type Modifier struct {
+	Kind     string
+	Value    int
+	Position int
+	Compare  string
+	Text     string
+}
+
+// Inspect parses an expression and returns its public form. It is InspectWith
+// without variables, so an expression with variables fails to report its range.
+func Inspect(expr string) (Expression, error) {
+	return InspectWith(expr, nil)
+}
+
+// InspectWith parses an expression and returns its public form, computing the
+// range of the total with the variables resolved from vars.
+func InspectWith(expr string, vars Resolver) (Expression, error) {
+	exp, err := lookupExpression(expr)
+	if err != nil {
+		return Expression{}, err
+	}
+	out := Expression{
+		Source:    expr,
+		Canonical: exp.canonical,
+		Groups:    make([]DiceGroup, len(exp.groups)),
+		Variables: exp.variables(),
+	}
+	ranges := make([]valueRange, len(exp.groups))
+	for i, g := range exp.groups {
+		if ranges[i], err = groupRange(g); err != nil {
+			return Expression{}, fmt.Errorf("range of %s: %w", g.String(), err)
+		}
+		out.Groups[i] = publicGroup(g, ranges[i])
+	}
+	total, err := nodeRange(exp.root, ranges, &env{vars: vars})
+	if err != nil {
+		return Expression{}, fmt.Errorf("range of %q: %w", expr, err)
+	}
+	out.Min, out.Max = total.lo, total.hi
+	return out, nil
+}
+
+func publicGroup(g *diceGroup, r valueRange) DiceGroup {
+	pg := DiceGroup{
+		Canonical: g.String(),
+		Count:     g.count,
+		Faces:     g.sides,
+		Type:      DiceType(g.diceType),
+		Die:       g.custom,
+		Min:       r.lo,
+		Max:       r.hi,
+	}
+	if g.faces != nil {
+		pg.Faces = len(g.faces)
+	}
+	switch g.explode.mode {
+	case explodeAdd:
+		pg.Explode, pg.ExplodeAt = explodeSuffix, g.explode.threshold
+	case explodeCompound:
+		pg.Explode, pg.ExplodeAt = compoundSuffix, g.explode.threshold
+	}
+	pg.Modifiers = make([]Modifier, len(g.mods))
+	for i, m := range g.mods {
+		pg.Modifiers[i] = publicModifier(m)
+	}
+	return pg
+}
+
+func publicModifier(m mod) Modifier {
+	pm := Modifier{Kind: m.name(), Text: m.String()}
+	switch mm := m.(type) {
+	case addConst:
+		pm.Value = mm.value
+	case addToEach:
+		pm.Value = mm.value
+	case addIndividual:
+		pm.Value, pm.Position = mm.value, mm.position
+	case dropLowest:
+		pm.Value = mm.quantity
+	case dropHighest:
+		pm.Value = mm.quantity
+	case keepHighest:
+		pm.Value = mm.quantity
+	case keepLowest:
+		pm.Value = mm.quantity
+	case divide:
+		pm.Value = mm.value
+	case multiply:
+		pm.Value = mm.value
+	case clampMin:
+		pm.Value = mm.value
+	case clampMax:
+		pm.Value = mm.value
+	case reroll:
+		pm.Value, pm.Compare = mm.test.target, mm.test.cmp
+	case countSuccesses:
+		pm.Value, pm.Compare = mm.target, mm.cmp
+	case concat:
+		pm.Value = mm.faces
+	}
+	return pm
+}
+
+// valueRange is a closed interval of integers.
+type valueRange struct {
+	lo, hi int
+}
+
+func (r valueRange) add(v int) valueRange {
+	return valueRange{min(r.lo, v), max(r.hi, v)}
+}
+
+// groupRange returns the smallest and largest value of a dice group.
+//
+// Every modifier but an exact success count is monotone in every die, so the extremes
+// come from pools where all dice show the same value: the lowest or the highest value
+// a die can keep after its rerolls, with the pool at its base size or, for exploding
+// dice, at its largest. Exact success counts also try pools showing the target.
+func groupRange(g *diceGroup) (valueRange, error) {
+	if len(g.dicepool.dice) == 0 {
+		out, err := runMods(nil, nil, nil, g.mods, nil)
+		return valueRange{sum(out...), sum(out...)}, err
+	}
+	d := g.dicepool.dice[0]
+	var mods []mod
+	var rerolls []reroll
+	for _, m := range g.mods {
+		if r, ok := m.(reroll); ok {
+			rerolls = append(rerolls, r)
+			continue
+		}
+		mods = append(mods, m)
+	}
+	kept := keptValues(d, rerolls)
+	if len(kept) == 0 {
+		return valueRange{}, fmt.Errorf("no face survives the rerolls")
+	}
+	values := []int{kept[0], kept[len(kept)-1]}
+	for _, m := range mods {
+		if c, ok := m.(countSuccesses); ok && c.cmp == cmpEqual && canKeep(d, rerolls, c.target) {
+			values = append(values, c.target)
+		}
+	}
+	sizes := []int{g.count}
+	chain := maxExplosionChain + 1
+	switch {
+	case g.explode.mode == explodeAdd && d.explodes(kept[len(kept)-1]):
+		sizes = append(sizes, g.count*chain)
+	case g.explode.mode == explodeCompound && d.explodes(kept[len(kept)-1]):
+		values = append(values, kept[len(kept)-1]*chain)
+	}
+	var r valueRange
+	first := true
+	for _, size := range sizes {
+		for _, v := range values {
+			pool := make([]int, size)
+			for i := range pool {
+				pool[i] = v
+			}
+			out, err := runMods(pool, nil, nil, mods, nil)
+			if err != nil {
+				return valueRange{}, err
+			}
+			total := sum(out...)
+			if first {
+				r, first = valueRange{total, total}, false
+				continue
+			}
+			r = r.add(total)
+		}
+	}
+	return r, nil
+}
+
+// keptValues returns the sorted values a die can end up with: a plain reroll never
+// keeps a matching value, a reroll once may keep anything. Numbered dice count up
+// from 1, so for them only the lowest and the highest kept face are returned.
+func keptValues(d die, rerolls []reroll) []int {
+	if d.values != nil {
+		var kept []int
+		for f := 1; f <= d.faces; f++ {
+			if v := d.value(f); canKeep(d, rerolls, v) {
+				kept = append(kept, v)
+			}
+		}
+		slices.Sort(kept)
+		return slices.Compact(kept)
+	}
+	var kept []int
+	for f := 1; f <= d.faces; f++ {
+		if canKeep(d, rerolls, f) {
+			kept = append(kept, f)
+			break
+		}
+	}
+	for f := d.faces; len(kept) == 1 && f > kept[0]; f-- {
+		if canKeep(d, rerolls, f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// canKeep reports whether a die can end its rerolls showing v.
+func canKeep(d die, rerolls []reroll, v int) bool {
+	if d.values != nil {
+		if !slices.Contains(d.values, v) {
+			return false
+		}
+	} else if v < 1 || v > d.faces {
+		return false
+	}
+	return !slices.ContainsFunc(rerolls, func(r reroll) bool { return !r.once && r.test.passes(v) })
+}
+
+// nodeRange evaluates the expression tree over intervals. Variables are resolved from e.
+func nodeRange(n node, groups []valueRange, e *env) (valueRange, error) {
+	switch nn := n.(type) {
+	case numberNode:
+		return valueRange{nn.value, nn.value}, nil
+	case varNode:
+		v, err := nn.eval(e)
+		return valueRange{v, v}, err
+	case diceNode:
+		return groups[nn.group.index], nil
+	case negNode:
+		r, err := nodeRange(nn.operand, groups, e)
+		return valueRange{-r.hi, -r.lo}, err
+	case binaryNode:
+		return binaryRange(nn.op, nn.left, nn.right, groups, e, func(l, r int) (int, error) {
+			return applyOperator(nn.op, l, r)
+		})
+	case funcNode:
+		if div, ok := nn.roundedDivision(); ok {
+			return binaryRange(opDiv, div.left, div.right, groups, e, func(l, r int) (int, error) {
+				return roundDivision(nn.name, l, r)
+			})
+		}
+		args := make([]valueRange, len(nn.args))
+		for i, arg := range nn.args {
+			r, err := nodeRange(arg, groups, e)
+			if err != nil {
+				return valueRange{}, err
+			}
+			args[i] = r
+		}
+		return funcRange(nn.name, args)
+	default:
+		return valueRange{}, fmt.Errorf("unknown node %T", n)
+	}
+}
+
+// binaryRange applies op to every combination of the ends of both operands. Division
+// by an operand that spans zero also tries -1 and 1, the divisors closest to zero.
+func binaryRange(op string, left, right node, groups []valueRange, e *env, apply func(l, r int) (int, error)) (valueRange, error) {
+	l, err := nodeRange(left, groups, e)
+	if err != nil {
+		return valueRange{}, err
+	}
+	r, err := nodeRange(right, groups, e)
+	if err != nil {
+		return valueRange{}, err
+	}
+	divisors := []int{r.lo, r.hi}
+	if op == opDiv {
+		if r.lo == 0 && r.hi == 0 {
+			return valueRange{}, fmt.Errorf("division by zero")
+		}
+		divisors = slices.DeleteFunc([]int{r.lo, r.hi, -1, 1}, func(v int) bool {
+			return v == 0 || v < r.lo || v > r.hi
+		})
+	}
+	var out valueRange
+	first := true
+	for _, a := range []int{l.lo, l.hi} {
+		for _, b := range divisors {
+			v, err := apply(a, b)
+			if err != nil {
+				return valueRange{}, err
+			}
+			if first {
+				out, first = valueRange{v, v}, false
+				continue
+			}
+			out = out.add(v)
+		}
+	}
+	return out, nil
+}
+
+func funcRange(name string, args []valueRange) (valueRange, error) {
+	switch name {
+	case funcMax:
+		out := args[0]
+		for _, a := range args[1:] {
+			out = valueRange{max(out.lo, a.lo), max(out.hi, a.hi)}
+		}
+		return out, nil
+	case funcMin:
+		out := args[0]
+		for _, a := range args[1:] {
+			out = valueRange{min(out.lo, a.lo), min(out.hi, a.hi)}
+		}
+		return out, nil
+	case funcAbs:
+		a := args[0]
+		switch {
+		case a.lo >= 0:
+			return a, nil
+		case a.hi <= 0:
+			return valueRange{-a.hi, -a.lo}, nil
+		default:
+			return valueRange{0, max(-a.lo, a.hi)}, nil
+		}
+	case funcFloor, funcCeil, funcRound:
+		return args[0], nil
+	default:
+		return valueRange{}, fmt.Errorf("unknown function %q", name)
+	}
+}
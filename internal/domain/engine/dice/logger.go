@@ -0,0 +1,167 @@
+package dice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Logger receives a structured record of every roll made by a Manager.
+// Implementations must be safe for concurrent use if the Manager is shared.
+type Logger interface {
+	LogRoll(RollRecord)
+}
+
+// Stage is the state of the dice after a single modifier was applied.
+// Group is the index of the dice group the modifier belongs to, in expression order.
+type Stage struct {
+	Group    int    `json:"group,omitempty"`
+	Modifier string `json:"modifier"`
+	Values   []int  `json:"values"`
+}
+
+// RollRecord is the transcript of a single roll: what was asked, what the dice showed,
+// how every modifier changed them and the value of every variable read.
+type RollRecord struct {
+	Expression string         `json:"expression"`
+	Seed       string         `json:"seed"`
+	Raw        []int          `json:"raw"`
+	Codes      []string       `json:"codes,omitempty"`
+	Explosions []Explosion    `json:"explosions,omitempty"`
+	Stages     []Stage        `json:"stages"`
+	Vars       map[string]int `json:"vars,omitempty"`
+	DMs        []int          `json:"dms,omitempty"`
+	Sum        int            `json:"sum"`
+	Code       string         `json:"code"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// String renders the record as a single human readable line, e.g.
+// "2d6+1 [3 5] -> sum [8] -> add to sum [9] = 9". Stages of expressions with several
+// dice groups are prefixed with the group number: "2d6+1d3 [3 5 2] -> #1 sum [8] -> #2 sum [2] = 10".
+func (rr RollRecord) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(rr.Expression)
+	if len(rr.Vars) > 0 {
+		names := slices.Sorted(maps.Keys(rr.Vars))
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf(" %s%s=%d", variablePrefix, name, rr.Vars[name]))
+		}
+	}
+	if len(rr.DMs) > 0 {
+		sb.WriteString(fmt.Sprintf(" DM%v", rr.DMs))
+	}
+	if rr.Error != "" {
+		sb.WriteString(": error: " + rr.Error)
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf(" %v", rr.Raw))
+	groups := slices.ContainsFunc(rr.Stages, func(st Stage) bool { return st.Group > 0 })
+	for _, st := range rr.Stages {
+		if groups {
+			sb.WriteString(fmt.Sprintf(" -> #%d %s %v", st.Group+1, st.Modifier, st.Values))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(" -> %s %v", st.Modifier, st.Values))
+	}
+	sb.WriteString(" = " + rr.Code)
+	return sb.String()
+}
+
+// record builds the transcript of the roll held in rs.
+func (m *Manager) record(rs *rollState, expr string, intrpr interpretation, err error) RollRecord {
+	rr := RollRecord{
+		Expression: expr,
+		Seed:       m.Seed(),
+		Raw:        slices.Clone(rs.result.raw),
+		Codes:      Result{codes: rs.result.codes}.Codes(),
+		Explosions: slices.Clone(rs.result.explosions),
+		Stages:     intrpr.stages,
+		Vars:       intrpr.vars,
+		DMs:        slices.Clone(rs.dms),
+		Sum:        intrpr.sum,
+		Code:       intrpr.code,
+	}
+	if err != nil {
+		rr.Error = err.Error()
+	}
+	return rr
+}
+
+// JSONLinesLogger writes every record as a JSON object on its own line.
+type JSONLinesLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONLinesLogger creates a Logger writing JSON Lines to w.
+func NewJSONLinesLogger(w io.Writer) *JSONLinesLogger {
+	return &JSONLinesLogger{enc: json.NewEncoder(w)}
+}
+
+// LogRoll encodes the record. The first write error is kept and reported by Err.
+func (l *JSONLinesLogger) LogRoll(rr RollRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	if err := l.enc.Encode(rr); err != nil {
+		l.err = fmt.Errorf("failed to write roll record: %w", err)
+	}
+}
+
+// Err returns the first error encountered while writing.
+func (l *JSONLinesLogger) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// MemoryLogger keeps every record in memory, in roll order.
+type MemoryLogger struct {
+	mu      sync.Mutex
+	records []RollRecord
+}
+
+// NewMemoryLogger creates an empty in-memory transcript.
+func NewMemoryLogger() *MemoryLogger {
+	return &MemoryLogger{}
+}
+
+// LogRoll appends the record to the transcript.
+func (l *MemoryLogger) LogRoll(rr RollRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, rr)
+}
+
+// Records returns a copy of the captured records.
+func (l *MemoryLogger) Records() []RollRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.records)
+}
+
+// Lines returns the transcript rendered with RollRecord.String.
+func (l *MemoryLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := make([]string, 0, len(l.records))
+	for _, rr := range l.records {
+		lines = append(lines, rr.String())
+	}
+	return lines
+}
+
+// Reset discards all captured records.
+func (l *MemoryLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = nil
+}
@@ -1,8 +1,10 @@
 package dice
 
 import (
+	"context"
 	"fmt"
 	"slices"
+	"strconv"
 )
 
 func newManager(r roller) *Manager {
@@ -10,70 +12,216 @@ func newManager(r roller) *Manager {
 		r = defaultRoller
 	}
 	return &Manager{
-		roller:    r,
-		rollState: newRollState(&stdInterpreter{}),
+		roller:      r,
+		interpreter: &stdInterpreter{},
+		cache:       exprCache,
+		budget:      DefaultBudget(),
 	}
 }
 
-func newRollState(i interpreter) *rollState {
+// newRollState creates the state of a single roll. Every call gets its own.
+func newRollState(ctx context.Context, b Budget, i interpreter, vars Resolver, dms []int) *rollState {
 	return &rollState{
 		result:      result{},
+		ctx:         ctx,
+		budget:      b,
+		vars:        vars,
+		dms:         dms,
 		interpreter: i,
 	}
 }
 
-// Result returns the last roll result. The caller must not modify the returned slices.
+// Result returns the result of the last roll that finished. When several goroutines
+// share the Manager that may be another goroutine's roll; RollResult returns the
+// result of a specific call. The caller must not modify the returned slices.
 // This method is safe for concurrent use.
 func (m *Manager) Result() Result {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	return Result{dice: m.rollState.result.dice, raw: m.rollState.result.raw}
-}
-
-// roll parses the expression (using cache) and performs the basic roll.
-// It must be called with the mutex held.
-func (m *Manager) roll(expr string) error {
-	expStruct, ok := exprCache.get(expr)
-	if !ok {
-		var err error
-		expStruct, err = newExpression(expr)
-		if err != nil {
-			return fmt.Errorf("failed to parse expression %q: %w", expr, err)
+	last := m.last.Load()
+	if last == nil {
+		return Result{}
+	}
+	return Result{
+		dice:       last.dice,
+		raw:        last.raw,
+		codes:      last.codes,
+		explosions: last.explosions,
+	}
+}
+
+// SetLogger attaches a Logger that receives a RollRecord for every roll. Nil disables logging.
+func (m *Manager) SetLogger(l Logger) {
+	if l == nil {
+		m.logger.Store(nil)
+		return
+	}
+	m.logger.Store(&l)
+}
+
+// Seed returns the identity of the seed the Manager rolls from.
+// Managers created with an empty seed report the random seed as "random:<n>".
+func (m *Manager) Seed() string {
+	if id, ok := m.roller.(identifier); ok {
+		return id.identity()
+	}
+	return ""
+}
+
+// roll parses the expression (using cache) and performs the basic roll into rs.
+// The dice are drawn through a budgetRoller, which stops the roll when the context of
+// rs is done or its budget is spent.
+func (m *Manager) roll(rs *rollState, expr string) error {
+	expStruct, err := m.cache.lookup(expr, true)
+	if err != nil {
+		return err
+	}
+	if err := rs.budget.checkPool(expStruct); err != nil {
+		return err
+	}
+	if err := rs.ctx.Err(); err != nil {
+		return err
+	}
+	var r roller = m.roller
+	if rec := m.recorder.Load(); rec != nil {
+		r = rec
+	}
+	br := &budgetRoller{roller: r, ctx: rs.ctx, budget: rs.budget}
+	r, rs.budgeted = br, br
+	rs.expression = expStruct
+	rs.groups = make([]result, len(expStruct.groups))
+	if len(expStruct.groups) == 1 {
+		rs.groups[0] = basicRoll(r, expStruct.groups[0].dicepool)
+		rs.result = rs.groups[0]
+		rs.roller = r
+		return rs.drawErr(m)
+	}
+	combined := result{}
+	for i, g := range expStruct.groups {
+		res := basicRoll(r, g.dicepool)
+		rs.groups[i] = res
+		for _, ex := range res.explosions {
+			combined.explosions = append(combined.explosions, Explosion{Index: ex.Index + len(combined.raw), Extra: ex.Extra})
+		}
+		combined.dice = append(combined.dice, res.dice...)
+		combined.codes = append(combined.codes, res.codes...)
+		combined.raw = append(combined.raw, res.raw...)
+	}
+	rs.result = combined
+	rs.roller = r
+	return rs.drawErr(m)
+}
+
+// sourceErr reports a failure of a random source that can run dry.
+func (m *Manager) sourceErr() error {
+	if f, ok := m.roller.(interface{ err() error }); ok {
+		if err := f.err(); err != nil {
+			return fmt.Errorf("random source failed: %w", err)
 		}
-		exprCache.set(expr, expStruct)
 	}
-	m.rollState.expression = expStruct
-	m.rollState.result = basicRoll(m.roller, m.rollState.expression.dicepool)
 	return nil
 }
 
+// evaluate rolls the expression and interprets the result with the variables and
+// external DMs. Every call is reported to the logger, if one is set, and becomes the
+// Manager's last result. It keeps no state between calls and needs no lock.
+func (m *Manager) evaluate(expr string, vars Resolver, dms []int) (interpretation, result, error) {
+	return m.evaluateContext(context.Background(), expr, vars, dms)
+}
+
+// evaluateContext is evaluate under ctx and the budget of ctx or of the Manager.
+func (m *Manager) evaluateContext(ctx context.Context, expr string, vars Resolver, dms []int) (interpretation, result, error) {
+	rs := newRollState(ctx, m.budgetFor(ctx), m.interpreter, vars, dms)
+	intrpr, err := m.evaluateState(rs, expr)
+	if l := m.logger.Load(); l != nil {
+		(*l).LogRoll(m.record(rs, expr, intrpr, err))
+	}
+	m.last.Store(&rs.result)
+	return intrpr, rs.result, err
+}
+
+func (m *Manager) evaluateState(rs *rollState, expr string) (interpretation, error) {
+	if err := m.roll(rs, expr); err != nil {
+		return interpretation{}, err
+	}
+	rs.budgeted.rerolling = true
+	intrpr, err := rs.interpreter.interpret(rs)
+	// A spent budget also makes rerolls fail, so it is reported first.
+	if err := rs.drawErr(m); err != nil {
+		return interpretation{}, err
+	}
+	if err != nil {
+		return interpretation{}, fmt.Errorf("roll state recovery failed: %w", err)
+	}
+	return intrpr, nil
+}
+
+// drawErr reports why the dice of the roll could not be drawn: a done context, a
+// spent budget or a failed random source.
+func (rs *rollState) drawErr(m *Manager) error {
+	if rs.budgeted != nil && rs.budgeted.err != nil {
+		return rs.budgeted.err
+	}
+	return m.sourceErr()
+}
+
+// rollState holds a single roll. result combines the dice of every group in
+// expression order; groups keeps them apart for the interpreter, and roller rolls
+// the extra dice modifiers such as rerolls ask for. budgeted is that roller seen as
+// the budgetRoller counting the draws under ctx and budget.
 type rollState struct {
 	expression  *expression
 	result      result
+	groups      []result
+	roller      roller
+	budgeted    *budgetRoller
+	ctx         context.Context
+	budget      Budget
+	vars        Resolver
+	dms         []int
 	interpreter interpreter
 }
 
+// expression is a parsed dice expression: an arithmetic tree over dice groups.
+// canonical is the normalised form of code, e.g. "2d6+1" for "2d6 + 1".
 type expression struct {
-	code     string
-	dicepool dicepool
-	mods     []mod
+	code      string
+	canonical string
+	root      node
+	groups    []*diceGroup
 }
 
 func newExpression(expr string) (*expression, error) {
-	dp, mods, err := parseExpression(expr)
+	exp, err := parseExpression(expr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse expression %q: %w", expr, err)
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidExpression, expr, err)
 	}
-	return &expression{
-		code:     expr,
-		dicepool: dp,
-		mods:     mods,
-	}, nil
+	return exp, nil
+}
+
+// variables returns the variable names of the expression in order of first appearance.
+func (e *expression) variables() []string {
+	names := []string{}
+	walk(e.root, func(n node) {
+		if v, ok := n.(varNode); ok && !slices.Contains(names, v.name) {
+			names = append(names, v.name)
+		}
+	})
+	return names
+}
+
+// concatGroup returns the dice group when the whole expression is a single concat group.
+func (e *expression) concatGroup() (*diceGroup, bool) {
+	n, ok := e.root.(diceNode)
+	if !ok || n.group.diceType != diceTypeConcat {
+		return nil, false
+	}
+	return n.group, true
 }
 
 type result struct {
-	dice []die
-	raw  []int
+	dice       []die
+	raw        []int
+	codes      []string
+	explosions []Explosion
 }
 
 type interpreter interface {
@@ -81,25 +229,66 @@ type interpreter interface {
 }
 
 type interpretation struct {
-	sum   int
-	code  string
-	valid bool
+	sum    int
+	code   string
+	valid  bool
+	stages []Stage
+	vars   map[string]int
 }
 
 type stdInterpreter struct{}
 
+// interpret applies the mods of every dice group in priority order, rolling the extra
+// dice rerolls ask for, and evaluates the expression tree over the group values and
+// variables. External DMs are added to the final sum, except for an expression that is
+// a single concat group, where they adjust the dice positionally (first DM to the first
+// die and so on) before the digits are joined.
 func (si stdInterpreter) interpret(rs *rollState) (interpretation, error) {
-	mid := slices.Clone(rs.result.raw)
-	var err error
-	for _, m := range rs.expression.mods {
-		mid, err = m.apply(mid)
+	cg, concatenated := rs.expression.concatGroup()
+	values := make([]int, len(rs.expression.groups))
+	code := ""
+	stages := []Stage{}
+	roll := func(d die) int { return d.value(rs.roller.roll(d)) }
+	for gi, g := range rs.expression.groups {
+		stage := func(m mod, values []int) {
+			stages = append(stages, Stage{Group: gi, Modifier: m.name(), Values: slices.Clone(values)})
+		}
+		mods := g.mods
+		var after []mod
+		if g == cg {
+			// Positional DMs go onto the dice right before they are joined.
+			k := slices.IndexFunc(mods, func(m mod) bool { _, ok := m.(concat); return ok })
+			mods, after = mods[:k], mods[k:]
+		}
+		mid, err := runMods(rs.groups[gi].raw, rs.groups[gi].dice, roll, mods, stage)
 		if err != nil {
 			return interpretation{}, fmt.Errorf("failed to apply mod: %w", err)
 		}
+		if g == cg {
+			cc := after[0].(concat)
+			for i, dm := range rs.dms {
+				if i < len(mid) {
+					mid[i] = cc.adjust(mid[i], dm)
+				}
+			}
+			code = cc.code(mid)
+			if mid, err = runMods(mid, nil, roll, after, stage); err != nil {
+				return interpretation{}, fmt.Errorf("failed to apply mod: %w", err)
+			}
+		}
+		values[gi] = sum(mid...)
+	}
+	e := &env{groups: values, vars: rs.vars}
+	total, err := rs.expression.root.eval(e)
+	if err != nil {
+		return interpretation{}, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+	if !concatenated {
+		total += sum(rs.dms...)
+		return interpretation{sum: total, code: strconv.Itoa(total), valid: true, stages: stages, vars: e.used}, nil
 	}
-	sum := 0
-	for _, v := range mid {
-		sum += v
+	if n, err := strconv.Atoi(code); err != nil || n != total {
+		code = fmt.Sprintf("%0*d", len(code), total)
 	}
-	return interpretation{sum: sum, code: fmt.Sprintf("%d", sum), valid: true}, nil
+	return interpretation{sum: total, code: code, valid: true, stages: stages, vars: e.used}, nil
 }
@@ -3,6 +3,8 @@ package dice
 import (
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -15,13 +17,31 @@ const (
 	modDivide        = "divide"
 	modMultiply      = "multiply"
 	modSum           = "sum"
+	modConcat        = "concat"
+	modCount         = "count successes"
+	modReroll        = "reroll"
+	modRerollOnce    = "reroll once"
+	modKeepHighest   = "keep highest"
+	modKeepLowest    = "keep lowest"
+	modClampMin      = "clamp min"
+	modClampMax      = "clamp max"
+
+	// maxRerolls caps the number of times a single die is rerolled.
+	maxRerolls = 100
 
 	priorityNone          = 0
+	priorityReroll        = 10
 	priorityAddIndividual = 20
 	priorityAddToEach     = 30
+	priorityClampMin      = 40
+	priorityClampMax      = 41
 	priorityDropLowest    = 70
 	priorityDropHighest   = 71
+	priorityKeepHighest   = 72
+	priorityKeepLowest    = 73
 	prioritySum           = 100
+	priorityConcat        = 100
+	priorityCount         = 100
 	priorityAddToSum      = 110
 	priorityDivide        = 120
 	priorityMultiply      = 130
@@ -30,16 +50,55 @@ const (
 type mod interface {
 	apply([]int) ([]int, error)
 	priority() int
+	name() string
+	// String returns the modifier in expression syntax, or "" for implicit modifiers.
+	String() string
+}
+
+// dieMod is a mod that needs the dice of the pool and may roll new ones.
+// values are aligned with dice; roll returns the value of a fresh roll of a die.
+type dieMod interface {
+	mod
+	applyDice(values []int, dice []die, roll func(die) int) ([]int, error)
+}
+
+// runMods applies the mods in order. Mods that roll dice get the pool and the roller;
+// they must come before any mod that changes the pool size, which the priority order
+// guarantees. stage is called after every mod.
+func runMods(values []int, dice []die, roll func(die) int, mods []mod, stage func(mod, []int)) ([]int, error) {
+	out := slices.Clone(values)
+	var err error
+	for _, m := range mods {
+		if dm, ok := m.(dieMod); ok {
+			if len(out) != len(dice) {
+				return nil, fmt.Errorf("%s needs one value per die, got %d values for %d dice", m.name(), len(out), len(dice))
+			}
+			out, err = dm.applyDice(out, dice, roll)
+		} else {
+			out, err = m.apply(out)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stage != nil {
+			stage(m, out)
+		}
+	}
+	return out, nil
 }
 
 type none struct{}
 
 func (m none) apply(raw []int) ([]int, error) { return raw, nil }
 func (m none) priority() int                  { return priorityNone }
+func (m none) name() string                   { return modNone }
+func (m none) String() string                 { return "" }
 
 type summ struct{}
 
-func (m summ) priority() int { return prioritySum }
+func (m summ) priority() int  { return prioritySum }
+func (m summ) name() string   { return modSum }
+func (m summ) String() string { return "" }
 func (m summ) apply(raw []int) ([]int, error) {
 	s := 0
 	for _, v := range raw {
@@ -48,9 +107,109 @@ func (m summ) apply(raw []int) ([]int, error) {
 	return []int{s}, nil
 }
 
+// concat reads every die as a fixed-width group of digits and joins them into one number.
+// A d10 showing 10 reads as 0; dice with more than 10 faces use as many digits as their
+// face count has and are zero padded.
+type concat struct {
+	faces int
+}
+
+func (m concat) priority() int  { return priorityConcat }
+func (m concat) name() string   { return modConcat }
+func (m concat) String() string { return "" }
+func (m concat) apply(raw []int) ([]int, error) {
+	code := m.code(raw)
+	if code == "" {
+		return nil, fmt.Errorf("no dice to concatenate")
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return nil, fmt.Errorf("concatenated value %q is out of range: %w", code, err)
+	}
+	return []int{n}, nil
+}
+
+// width returns the number of digits every die contributes.
+func (m concat) width() int {
+	if m.faces <= 10 {
+		return 1
+	}
+	return len(strconv.Itoa(m.faces))
+}
+
+// digit normalises a single die value to fit its digit group.
+func (m concat) digit(v int) int {
+	if m.faces == 10 && v == 10 {
+		return 0
+	}
+	return setBounds(v, 0, m.limit()-1)
+}
+
+// limit returns the first value that does not fit a digit group.
+func (m concat) limit() int {
+	limit := 1
+	for range m.width() {
+		limit *= 10
+	}
+	return limit
+}
+
+// adjust applies a positional DM to a die: the die is read as its digit first, so a
+// d10 showing 10 reads 0 and +1 makes it 1, and the result is clamped to the digit range.
+func (m concat) adjust(v, dm int) int {
+	return setBounds(m.digit(v)+dm, 0, m.limit()-1)
+}
+
+// code joins the dice into a string, keeping leading zeros.
+func (m concat) code(raw []int) string {
+	sb := strings.Builder{}
+	for _, v := range raw {
+		sb.WriteString(fmt.Sprintf("%0*d", m.width(), m.digit(v)))
+	}
+	return sb.String()
+}
+
+// countSuccesses replaces the sum with the number of dice that pass a comparison
+// against target, e.g. ">=5" for "6d6>=5".
+type countSuccesses struct {
+	cmp    string
+	target int
+}
+
+func (m countSuccesses) priority() int  { return priorityCount }
+func (m countSuccesses) name() string   { return modCount }
+func (m countSuccesses) String() string { return "" }
+func (m countSuccesses) apply(raw []int) ([]int, error) {
+	n := 0
+	for _, v := range raw {
+		if m.passes(v) {
+			n++
+		}
+	}
+	return []int{n}, nil
+}
+
+// passes reports whether a single die value counts as a success.
+func (m countSuccesses) passes(v int) bool {
+	switch m.cmp {
+	case cmpGreaterEqual:
+		return v >= m.target
+	case cmpLessEqual:
+		return v <= m.target
+	case cmpGreater:
+		return v > m.target
+	case cmpLess:
+		return v < m.target
+	default:
+		return v == m.target
+	}
+}
+
 type addConst struct{ value int }
 
-func (m addConst) priority() int { return priorityAddToSum }
+func (m addConst) priority() int  { return priorityAddToSum }
+func (m addConst) name() string   { return modAddToSum }
+func (m addConst) String() string { return fmt.Sprintf("%+d", m.value) }
 func (m addConst) apply(raw []int) ([]int, error) {
 	out := make([]int, len(raw))
 	for i, v := range raw {
@@ -61,7 +220,9 @@ func (m addConst) apply(raw []int) ([]int, error) {
 
 type addToEach struct{ value int }
 
-func (m addToEach) priority() int { return priorityAddToEach }
+func (m addToEach) priority() int  { return priorityAddToEach }
+func (m addToEach) name() string   { return modAddToEach }
+func (m addToEach) String() string { return fmt.Sprintf("%d%s", m.value, addEachSuffix) }
 func (m addToEach) apply(raw []int) ([]int, error) {
 	out := make([]int, len(raw))
 	for i, v := range raw {
@@ -76,6 +237,10 @@ type addIndividual struct {
 }
 
 func (m addIndividual) priority() int { return priorityAddIndividual }
+func (m addIndividual) name() string  { return modAddIndividual }
+func (m addIndividual) String() string {
+	return fmt.Sprintf("%d%s%d", m.value, addIndividualSuffix, m.position)
+}
 func (m addIndividual) apply(raw []int) ([]int, error) {
 	if m.position < 1 || m.position > len(raw) {
 		return nil, fmt.Errorf("position %d out of range (1..%d)", m.position, len(raw))
@@ -93,7 +258,9 @@ func (m addIndividual) apply(raw []int) ([]int, error) {
 
 type dropLowest struct{ quantity int }
 
-func (m dropLowest) priority() int { return priorityDropLowest }
+func (m dropLowest) priority() int  { return priorityDropLowest }
+func (m dropLowest) name() string   { return modDropLowest }
+func (m dropLowest) String() string { return fmt.Sprintf("%s%d", dropLowPrefix, m.quantity) }
 func (m dropLowest) apply(raw []int) ([]int, error) {
 	if m.quantity < 0 {
 		return nil, fmt.Errorf("drop quantity cannot be negative: %d", m.quantity)
@@ -111,7 +278,9 @@ func (m dropLowest) apply(raw []int) ([]int, error) {
 
 type dropHighest struct{ quantity int }
 
-func (m dropHighest) priority() int { return priorityDropHighest }
+func (m dropHighest) priority() int  { return priorityDropHighest }
+func (m dropHighest) name() string   { return modDropHighest }
+func (m dropHighest) String() string { return fmt.Sprintf("%s%d", dropHighPrefix, m.quantity) }
 func (m dropHighest) apply(raw []int) ([]int, error) {
 	if m.quantity < 0 {
 		return nil, fmt.Errorf("drop quantity cannot be negative: %d", m.quantity)
@@ -129,7 +298,9 @@ func (m dropHighest) apply(raw []int) ([]int, error) {
 
 type divide struct{ value int }
 
-func (m divide) priority() int { return priorityDivide }
+func (m divide) priority() int  { return priorityDivide }
+func (m divide) name() string   { return modDivide }
+func (m divide) String() string { return fmt.Sprintf("%s%d", dividePrefix, m.value) }
 func (m divide) apply(raw []int) ([]int, error) {
 	if m.value == 0 {
 		return nil, fmt.Errorf("division by zero")
@@ -143,7 +314,9 @@ func (m divide) apply(raw []int) ([]int, error) {
 
 type multiply struct{ value int }
 
-func (m multiply) priority() int { return priorityMultiply }
+func (m multiply) priority() int  { return priorityMultiply }
+func (m multiply) name() string   { return modMultiply }
+func (m multiply) String() string { return fmt.Sprintf("%s%d", multiplyPrefix1, m.value) }
 func (m multiply) apply(raw []int) ([]int, error) {
 	out := make([]int, len(raw))
 	for i, v := range raw {
@@ -151,3 +324,112 @@ func (m multiply) apply(raw []int) ([]int, error) {
 	}
 	return out, nil
 }
+
+// reroll rolls a die again while its value matches the test, or only once.
+type reroll struct {
+	test countSuccesses
+	once bool
+}
+
+func (m reroll) priority() int { return priorityReroll }
+func (m reroll) name() string {
+	if m.once {
+		return modRerollOnce
+	}
+	return modReroll
+}
+func (m reroll) String() string {
+	prefix := rerollPrefix
+	if m.once {
+		prefix = rerollOncePrefix
+	}
+	if m.test.cmp == cmpEqual {
+		return fmt.Sprintf("%s%d", prefix, m.test.target)
+	}
+	return fmt.Sprintf("%s%s%d", prefix, m.test.cmp, m.test.target)
+}
+func (m reroll) apply([]int) ([]int, error) {
+	return nil, fmt.Errorf("%s needs dice to roll", m.name())
+}
+func (m reroll) applyDice(values []int, dice []die, roll func(die) int) ([]int, error) {
+	out := slices.Clone(values)
+	for i, d := range dice {
+		for n := 0; m.test.passes(out[i]); n++ {
+			if n == maxRerolls {
+				return nil, fmt.Errorf("die %d still matches %s after %d rerolls", i+1, m.String(), maxRerolls)
+			}
+			out[i] = roll(d)
+			if m.once {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// matchesEvery reports whether the test matches every face of the die, which would
+// make an unlimited reroll loop forever.
+func (m reroll) matchesEvery(d die) bool {
+	for f := 1; f <= d.faces; f++ {
+		if !m.test.passes(d.value(f)) {
+			return false
+		}
+	}
+	return true
+}
+
+type keepHighest struct{ quantity int }
+
+func (m keepHighest) priority() int  { return priorityKeepHighest }
+func (m keepHighest) name() string   { return modKeepHighest }
+func (m keepHighest) String() string { return fmt.Sprintf("%s%d", keepHighPrefix, m.quantity) }
+func (m keepHighest) apply(raw []int) ([]int, error) {
+	if m.quantity < 1 || m.quantity > len(raw) {
+		return nil, fmt.Errorf("cannot keep %d dice from pool of %d", m.quantity, len(raw))
+	}
+	out := slices.Clone(raw)
+	slices.Sort(out)
+	return out[len(out)-m.quantity:], nil
+}
+
+type keepLowest struct{ quantity int }
+
+func (m keepLowest) priority() int  { return priorityKeepLowest }
+func (m keepLowest) name() string   { return modKeepLowest }
+func (m keepLowest) String() string { return fmt.Sprintf("%s%d", keepLowPrefix, m.quantity) }
+func (m keepLowest) apply(raw []int) ([]int, error) {
+	if m.quantity < 1 || m.quantity > len(raw) {
+		return nil, fmt.Errorf("cannot keep %d dice from pool of %d", m.quantity, len(raw))
+	}
+	out := slices.Clone(raw)
+	slices.Sort(out)
+	return out[:m.quantity], nil
+}
+
+// clampMin raises every die below value to value.
+type clampMin struct{ value int }
+
+func (m clampMin) priority() int  { return priorityClampMin }
+func (m clampMin) name() string   { return modClampMin }
+func (m clampMin) String() string { return fmt.Sprintf("%s%d", clampMinPrefix, m.value) }
+func (m clampMin) apply(raw []int) ([]int, error) {
+	out := make([]int, len(raw))
+	for i, v := range raw {
+		out[i] = max(v, m.value)
+	}
+	return out, nil
+}
+
+// clampMax lowers every die above value to value.
+type clampMax struct{ value int }
+
+func (m clampMax) priority() int  { return priorityClampMax }
+func (m clampMax) name() string   { return modClampMax }
+func (m clampMax) String() string { return fmt.Sprintf("%s%d", clampMaxPrefix, m.value) }
+func (m clampMax) apply(raw []int) ([]int, error) {
+	out := make([]int, len(raw))
+	for i, v := range raw {
+		out[i] = min(v, m.value)
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package dice
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,6 +12,9 @@ const (
 	diceTypeNormal       = "d"
 	diceTypeConcat       = "D"
 	diceTypeDestructive  = "DD"
+	explodeSuffix        = "!"
+	compoundSuffix       = "!!"
+	thresholdPrefix      = ">"
 	addEachSuffix        = "e"
 	addIndividualSuffix  = ">>"
 	dropLowPrefix        = "dl"
@@ -19,149 +23,569 @@ const (
 	multiplyPrefix1      = "x"
 	multiplyPrefix2      = "*"
 	complexModsSeparator = ":"
+	variablePrefix       = "@"
+	rerollPrefix         = "r"
+	rerollOncePrefix     = "ro"
+	keepHighPrefix       = "kh"
+	keepLowPrefix        = "kl"
+	clampMinPrefix       = "min"
+	clampMaxPrefix       = "max"
+	customDieOpen        = "["
+	customDieClose       = "]"
+	cmpGreaterEqual      = ">="
+	cmpLessEqual         = "<="
+	cmpGreater           = ">"
+	cmpLess              = "<"
+	cmpEqual             = "="
 )
 
-// parseExpression converts a dice expression string into a dicepool and a list of mods.
-func parseExpression(expr string) (dicepool, []mod, error) {
-	expr = strings.TrimSpace(expr)
-	if expr == "" {
-		return dicepool{}, nil, fmt.Errorf("empty expression")
+// modTokenPatterns match a single complex modifier at the start of the input, so a
+// modifier chain can be followed by arithmetic: "4d6:dl1+2" is (4d6:dl1)+2.
+var modTokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^ro?(>=|<=|>|<|=)?-?\d+`),
+	regexp.MustCompile(`^k[hl]\d+`),
+	regexp.MustCompile(`^m(in|ax)-?\d+`),
+	regexp.MustCompile(`^dl\d+`),
+	regexp.MustCompile(`^dh\d+`),
+	regexp.MustCompile(`^[+-]?\d+>>\d+`),
+	regexp.MustCompile(`^[+-]?\d+e`),
+	regexp.MustCompile(`^/\d+`),
+	regexp.MustCompile(`^[x*]-?\d+`),
+}
+
+// parser is a recursive descent parser over the expression grammar:
+//
+//	expression → sum [ ":" modifier ]*
+//	sum        → product ( ("+" | "-") product )*
+//	product    → unary ( ("*" | "/") unary )*
+//	unary      → ("-" | "+") unary | postfix
+//	postfix    → primary [ ":" modifier ]*
+//	primary    → number | dice | variable | "(" sum ")" | function "(" sum ( "," sum )* ")"
+//	variable   → "@" name
+//	dice       → [count] ("d" | "D" | "DD") (sides [ explode ] | "F" | "[" name "]") [ success ]
+type parser struct {
+	src    string
+	pos    int
+	groups []*diceGroup
+}
+
+// parseExpression converts a dice expression string into an expression tree.
+func parseExpression(expr string) (*expression, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	p := &parser{src: expr}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == ':' {
+		chain, err := p.parseChain()
+		if err != nil {
+			return nil, err
+		}
+		if root, err = foldModifiers(root, chain); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("unexpected characters in expression: %q", p.src[p.pos:])
+	}
+	if len(p.groups) == 0 {
+		return nil, fmt.Errorf("expression has no dice")
+	}
+	// A single dice group with additive constants keeps the constant as its own
+	// modifier, so "2d6+1" rolls, logs and explains exactly like "2d6" plus "add to sum".
+	// A constant written after a modifier chain ("(3d6:x2)+1") is added to the
+	// result of the chain and stays an addition.
+	if len(p.groups) == 1 && len(p.groups[0].chain) == 0 {
+		if folded, err := foldModifiers(root, nil); err == nil {
+			root = folded
+		}
+	}
+	for _, g := range p.groups {
+		if err := g.build(); err != nil {
+			return nil, err
+		}
 	}
+	if _, ok := root.(diceNode); !ok {
+		for _, g := range p.groups {
+			if g.diceType == diceTypeConcat {
+				return nil, fmt.Errorf("concat dice %s must be the whole expression", g.String())
+			}
+		}
+	}
+	return &expression{
+		code:      expr,
+		canonical: root.String(),
+		root:      root,
+		groups:    p.groups,
+	}, nil
+}
 
-	dicePart, modsPart := "", ""
-	if before, after, ok := strings.Cut(expr, ":"); ok {
-		dicePart = before
-		modsPart = after
-	} else {
-		dicePart = expr
+// peek skips whitespace and returns the next character, or 0 at the end of input.
+func (p *parser) peek() byte {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return 0
 	}
+	return p.src[p.pos]
+}
 
-	count, diceType, sides, leftover, err := parseDicePart(dicePart)
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
 	if err != nil {
-		return dicepool{}, nil, err
+		return nil, err
+	}
+	for {
+		ch := p.peek()
+		if ch != '+' && ch != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: string(ch), left: left, right: right}
 	}
+}
 
-	var simpleAdd *addConst
-	if strings.TrimSpace(leftover) != "" {
-		val, err := parseSimpleAdditive(leftover)
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ch := p.peek()
+		if ch != '*' && ch != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
 		if err != nil {
-			return dicepool{}, nil, fmt.Errorf("invalid simple additive modifier '%s': %w", leftover, err)
+			return nil, err
 		}
-		simpleAdd = &addConst{value: val}
+		left = binaryNode{op: string(ch), left: left, right: right}
 	}
+}
 
-	dice := make([]die, count)
-	for i := range count {
-		switch diceType {
-		case diceTypeNormal:
-			dice[i] = newDie(sides)
-		case diceTypeConcat, diceTypeDestructive:
-			return dicepool{}, nil, fmt.Errorf("special dice types not implemented")
-		default:
-			return dicepool{}, nil, fmt.Errorf("unknown dice type %s", diceType)
+func (p *parser) parseUnary() (node, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
 		}
+		return negNode{operand: operand}, nil
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, isNumber := n.(numberNode); isNumber || p.peek() != ':' {
+		return n, nil
 	}
+	chain, err := p.parseChain()
+	if err != nil {
+		return nil, err
+	}
+	return foldModifiers(n, chain)
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	ch := p.peek()
+	switch {
+	case ch == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case ch == '(':
+		p.pos++
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis at position %d", p.pos)
+		}
+		p.pos++
+		return inner, nil
+	case isDigit(ch):
+		start := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos < len(p.src) && (p.src[p.pos] == 'd' || p.src[p.pos] == 'D') {
+			p.pos = start
+			return p.parseDice()
+		}
+		n, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", p.src[start:p.pos])
+		}
+		return numberNode{value: n}, nil
+	case ch == 'd' || ch == 'D':
+		return p.parseDice()
+	case ch == variablePrefix[0]:
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos]) || p.src[p.pos] == '_') {
+			p.pos++
+		}
+		if start == p.pos || isDigit(p.src[start]) {
+			return nil, fmt.Errorf("invalid variable name at position %d", start)
+		}
+		return varNode{name: p.src[start:p.pos]}, nil
+	case isLetter(ch):
+		return p.parseFunction()
+	default:
+		return nil, fmt.Errorf("invalid dice type: unexpected %q", ch)
+	}
+}
+
+func (p *parser) parseFunction() (node, error) {
+	start := p.pos
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	arity, ok := functionArity[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid dice type: %q", name)
+	}
+	if p.peek() != '(' {
+		return nil, fmt.Errorf("missing '(' after %s", name)
+	}
+	p.pos++
+	args := []node{}
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing closing parenthesis after %s arguments", name)
+	}
+	p.pos++
+	if len(args) < arity[0] || (arity[1] >= 0 && len(args) > arity[1]) {
+		return nil, fmt.Errorf("function %s: wrong number of arguments: %d", name, len(args))
+	}
+	return funcNode{name: name, args: args}, nil
+}
 
-	complexMods, err := parseComplexModifiers(modsPart)
+func (p *parser) parseDice() (node, error) {
+	g, rest, err := parseDicePart(p.src[p.pos:])
 	if err != nil {
-		return dicepool{}, nil, err
+		return nil, err
 	}
+	p.pos = len(p.src) - len(rest)
+	g.index = len(p.groups)
+	p.groups = append(p.groups, g)
+	return diceNode{group: g}, nil
+}
 
-	allMods := []mod{}
-	allMods = append(allMods, complexMods...)
-	if simpleAdd != nil {
-		allMods = append(allMods, *simpleAdd)
+// parseChain reads ":mod:mod..." and returns the modifiers in written order.
+func (p *parser) parseChain() ([]mod, error) {
+	var chain []mod
+	for p.peek() == ':' {
+		p.pos++
+		p.peek()
+		token := ""
+		rest := p.src[p.pos:]
+		for _, re := range modTokenPatterns {
+			if loc := re.FindStringIndex(rest); loc != nil && isModBoundary(rest[loc[1]:]) {
+				token = rest[:loc[1]]
+				break
+			}
+		}
+		if token == "" {
+			end := strings.IndexAny(rest, ":(), \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			token = rest[:end]
+		}
+		if token == "" {
+			return nil, fmt.Errorf("empty complex modifier at position %d", p.pos)
+		}
+		m, err := parseOneComplexModifier(token)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, m)
+		p.pos += len(token)
 	}
-	allMods = append(allMods, summ{})
-	allMods = sortModifiers(allMods)
+	return chain, nil
+}
+
+// isModBoundary reports whether a modifier token may end right before rest.
+func isModBoundary(rest string) bool {
+	return rest == "" || strings.ContainsRune(":(),+-*/ \t", rune(rest[0]))
+}
+
+// foldModifiers attaches a modifier chain to the single dice group of n.
+// Additive constants around the group become part of it, so "3d6+2:/2" keeps its
+// meaning of (3d6+2)/2 with the modifiers applied in priority order.
+func foldModifiers(n node, chain []mod) (node, error) {
+	var group *diceGroup
+	constant := 0
+	var walk func(n node, sign int) error
+	walk = func(n node, sign int) error {
+		switch nn := n.(type) {
+		case diceNode:
+			if group != nil || sign < 0 {
+				return fmt.Errorf("modifiers need a single added dice group, got %q", n.String())
+			}
+			group = nn.group
+		case numberNode:
+			constant += sign * nn.value
+		case negNode:
+			return walk(nn.operand, -sign)
+		case binaryNode:
+			if nn.op != opAdd && nn.op != opSub {
+				return fmt.Errorf("modifiers cannot be applied to %q", nn.String())
+			}
+			if err := walk(nn.left, sign); err != nil {
+				return err
+			}
+			if nn.op == opSub {
+				return walk(nn.right, -sign)
+			}
+			return walk(nn.right, sign)
+		default:
+			return fmt.Errorf("modifiers cannot be applied to %q", n.String())
+		}
+		return nil
+	}
+	if err := walk(n, 1); err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("modifiers need a dice group, got %q", n.String())
+	}
+	group.constant += constant
+	group.chain = append(group.chain, chain...)
+	return diceNode{group: group}, nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
 
-	dp := newDicepool(dice...)
-	return dp, allMods, nil
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
 }
 
-func parseDicePart(part string) (count int, diceType string, sides int, leftover string, err error) {
-	count = 1
-	if len(part) > 0 && part[0] >= '0' && part[0] <= '9' {
+// parseDicePart reads "[count](d|D|DD)(sides|F|[name])[explode][success]" from the
+// start of part and returns the dice group and the unread remainder.
+func parseDicePart(part string) (*diceGroup, string, error) {
+	g := &diceGroup{count: 1}
+	counted := len(part) > 0 && isDigit(part[0])
+	if counted {
 		i := 0
-		for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+		for i < len(part) && isDigit(part[i]) {
 			i++
 		}
-		count, err = strconv.Atoi(part[:i])
+		count, err := strconv.Atoi(part[:i])
 		if err != nil {
-			return 0, "", 0, "", fmt.Errorf("invalid count: %s", part[:i])
+			return nil, "", fmt.Errorf("invalid count: %s", part[:i])
 		}
+		g.count = count
 		part = part[i:]
 	}
 	if len(part) == 0 {
-		return 0, "", 0, "", fmt.Errorf("missing dice type")
+		return nil, "", fmt.Errorf("missing dice type")
 	}
 
 	switch {
 	case strings.HasPrefix(part, diceTypeDestructive):
-		diceType = diceTypeDestructive
+		g.diceType = diceTypeDestructive
 	case strings.HasPrefix(part, diceTypeConcat):
-		diceType = diceTypeConcat
+		g.diceType = diceTypeConcat
 	case strings.HasPrefix(part, diceTypeNormal):
-		diceType = diceTypeNormal
+		g.diceType = diceTypeNormal
 	default:
-		return 0, "", 0, "", fmt.Errorf("invalid dice type")
+		return nil, "", fmt.Errorf("invalid dice type")
 	}
-	part = strings.TrimPrefix(part, diceType)
+	part = strings.TrimPrefix(part, g.diceType)
 
-	if len(part) == 0 || part[0] < '0' || part[0] > '9' {
-		return 0, "", 0, "", fmt.Errorf("missing sides after %s", diceType)
-	}
-	i := 0
-	for i < len(part) && part[i] >= '0' && part[i] <= '9' {
-		i++
-	}
-	sides, err = strconv.Atoi(part[:i])
-	if err != nil || sides < 1 {
-		return 0, "", 0, "", fmt.Errorf("invalid sides: %s", part[:i])
+	switch {
+	case g.diceType == diceTypeNormal && strings.HasPrefix(part, fudgeDie):
+		g.custom = fudgeDie
+		part = part[len(fudgeDie):]
+	case g.diceType == diceTypeNormal && strings.HasPrefix(part, customDieOpen):
+		end := strings.Index(part, customDieClose)
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing %s after custom die name", customDieClose)
+		}
+		g.custom = part[len(customDieOpen):end]
+		part = part[end+len(customDieClose):]
 	}
-	part = part[i:]
+	if g.custom != "" {
+		faces, ok := customDice.get(g.custom)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown custom die %q", g.custom)
+		}
+		g.faces = faces
+		g.sides = len(faces)
+	} else {
+		if len(part) == 0 || !isDigit(part[0]) {
+			return nil, "", fmt.Errorf("missing sides after %s", g.diceType)
+		}
+		i := 0
+		for i < len(part) && isDigit(part[i]) {
+			i++
+		}
+		sides, err := strconv.Atoi(part[:i])
+		if err != nil || sides < 1 {
+			return nil, "", fmt.Errorf("invalid sides: %s", part[:i])
+		}
+		g.sides = sides
+		if g.diceType == diceTypeConcat && !counted && sides > 10 {
+			// A bare concat die spells one die per digit: "D66" is 2D6, "D666" 3D6.
+			spec := part[:i]
+			if strings.Count(spec, spec[:1]) != len(spec) || spec[0] < '2' {
+				return nil, "", fmt.Errorf("concat die %s%s has more than 10 faces: write the count (2D6) or roll it with Digits", g.diceType, spec)
+			}
+			g.count, g.sides = len(spec), int(spec[0]-'0')
+		}
+		part = part[i:]
 
-	leftover = strings.TrimSpace(part)
-	for _, ch := range leftover {
-		if ch != '+' && ch != '-' && ch != ' ' && (ch < '0' || ch > '9') {
-			return 0, "", 0, "", fmt.Errorf("unexpected characters in additive part: %q", leftover)
+		var ex explosion
+		ex, part, err = parseExplosion(part, g.sides)
+		if err != nil {
+			return nil, "", err
 		}
+		g.explode = ex
 	}
-	return count, diceType, sides, leftover, nil
-}
 
-func parseSimpleAdditive(s string) (int, error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return 0, nil
+	success, part, err := parseSuccess(part)
+	if err != nil {
+		return nil, "", err
 	}
-	if s[0] == '+' {
-		s = s[1:]
+	g.success = success
+	if len(part) > 0 && (isLetter(part[0]) || isDigit(part[0]) || part[0] == '!' || part[0] == '[') {
+		return nil, "", fmt.Errorf("unexpected characters after dice: %q", part)
 	}
-	return strconv.Atoi(s)
+	return g, part, nil
 }
 
-func parseComplexModifiers(modsStr string) ([]mod, error) {
-	if modsStr == "" {
-		return nil, nil
-	}
-	parts := strings.Split(modsStr, complexModsSeparator)
-	var mods []mod
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
+// parseSuccess reads an optional success test such as ">=5" or "=6".
+func parseSuccess(part string) (*countSuccesses, string, error) {
+	for _, cmp := range []string{cmpGreaterEqual, cmpLessEqual, cmpGreater, cmpLess, cmpEqual} {
+		rest, ok := strings.CutPrefix(part, cmp)
+		if !ok {
 			continue
 		}
-		m, err := parseOneComplexModifier(p)
+		i := 0
+		if i < len(rest) && rest[i] == '-' {
+			i++
+		}
+		for i < len(rest) && isDigit(rest[i]) {
+			i++
+		}
+		target, err := strconv.Atoi(rest[:i])
 		if err != nil {
-			return nil, err
+			return nil, "", fmt.Errorf("invalid success target: %q", part)
 		}
-		mods = append(mods, m)
+		return &countSuccesses{cmp: cmp, target: target}, rest[i:], nil
 	}
-	return mods, nil
+	return nil, part, nil
+}
+
+// parseExplosion consumes an optional "!", "!!" or "!>N" / "!!>N" suffix that follows the sides.
+// Without a threshold dice explode on their maximum face.
+func parseExplosion(part string, sides int) (explosion, string, error) {
+	ex := explosion{}
+	switch {
+	case strings.HasPrefix(part, compoundSuffix):
+		ex.mode = explodeCompound
+		part = strings.TrimPrefix(part, compoundSuffix)
+	case strings.HasPrefix(part, explodeSuffix):
+		ex.mode = explodeAdd
+		part = strings.TrimPrefix(part, explodeSuffix)
+	default:
+		return ex, part, nil
+	}
+	ex.threshold = sides
+	if strings.HasPrefix(part, thresholdPrefix) && !strings.HasPrefix(part, cmpGreaterEqual) {
+		part = strings.TrimPrefix(part, thresholdPrefix)
+		i := 0
+		for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+			i++
+		}
+		n, err := strconv.Atoi(part[:i])
+		if err != nil {
+			return explosion{}, "", fmt.Errorf("invalid explosion threshold: %q", part)
+		}
+		ex.threshold = n
+		part = part[i:]
+	}
+	if ex.threshold < 2 || ex.threshold > sides {
+		return explosion{}, "", fmt.Errorf("explosion threshold %d out of range (2..%d)", ex.threshold, sides)
+	}
+	return ex, part, nil
 }
 
 func parseOneComplexModifier(modStr string) (mod, error) {
+	switch {
+	case strings.HasPrefix(modStr, keepHighPrefix), strings.HasPrefix(modStr, keepLowPrefix):
+		n, err := strconv.Atoi(modStr[2:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid keep count: %s", modStr)
+		}
+		if strings.HasPrefix(modStr, keepHighPrefix) {
+			return keepHighest{quantity: n}, nil
+		}
+		return keepLowest{quantity: n}, nil
+	case strings.HasPrefix(modStr, clampMinPrefix), strings.HasPrefix(modStr, clampMaxPrefix):
+		n, err := strconv.Atoi(modStr[3:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid clamp value: %s", modStr)
+		}
+		if strings.HasPrefix(modStr, clampMinPrefix) {
+			return clampMin{value: n}, nil
+		}
+		return clampMax{value: n}, nil
+	case strings.HasPrefix(modStr, rerollPrefix):
+		m := reroll{}
+		rest := modStr[len(rerollPrefix):]
+		if after, ok := strings.CutPrefix(modStr, rerollOncePrefix); ok {
+			m.once = true
+			rest = after
+		}
+		test, left, err := parseSuccess(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reroll test: %s", modStr)
+		}
+		if test == nil {
+			target, err := strconv.Atoi(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid reroll test: %s", modStr)
+			}
+			test, left = &countSuccesses{cmp: cmpEqual, target: target}, ""
+		}
+		if left != "" {
+			return nil, fmt.Errorf("invalid reroll test: %s", modStr)
+		}
+		m.test = *test
+		return m, nil
+	}
 	if before, ok := strings.CutSuffix(modStr, addEachSuffix); ok {
 		numStr := before
 		val, err := strconv.Atoi(numStr)
@@ -236,8 +660,20 @@ func sortModifiers(mods []mod) []mod {
 	return mods
 }
 
+// Canonical returns the normalised form of a dice expression: whitespace removed,
+// implicit counts written out and modifiers in the order they are applied,
+// e.g. "d6 + 2d6" becomes "1d6+2d6" and "4d6:+1e:dl1" becomes "4d6:1e:dl1".
+func Canonical(expr string) (string, error) {
+	exp, err := lookupExpression(expr)
+	if err != nil {
+		return "", err
+	}
+	return exp.canonical, nil
+}
+
 // ValidateExpression checks whether a dice expression is syntactically valid.
+// The error wraps ErrInvalidExpression.
 func ValidateExpression(expr string) error {
-	_, _, err := parseExpression(expr)
+	_, err := newExpression(expr)
 	return err
 }
@@ -0,0 +1,200 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// reverseSamples is the number of plain rolls RollBetween tries before it starts
+	// adjusting the dice of the closest one.
+	reverseSamples = 1000
+	// maxReverseAttempts caps the rolls RollBetween evaluates in total.
+	maxReverseAttempts = 20000
+	// reverseLabel prefixes the label of the stream RollBetween searches on.
+	reverseLabel = "reverse:"
+)
+
+// ErrUnreachable fails a RollBetween or Force whose range the expression cannot roll.
+var ErrUnreachable = errors.New("total out of reach")
+
+// RollBetween rolls the expression with the total, modifiers included, constrained to
+// [lo, hi], e.g. to honour an attribute fixed by the user while still producing dice.
+//
+// It rolls the expression up to reverseSamples times, which samples the totals in the
+// range with their true odds. When none lands in the range, it keeps the closest roll
+// and changes its dice one at a time until the total fits, so even unlikely totals
+// get a valid dice trail. The search runs on a stream seeded by one draw of the
+// Manager and nothing else, so a recording in progress holds only that draw and a
+// replay of it finds the same dice. The chosen dice are then rolled as a normal roll
+// of the Manager for the Logger and Result. The error wraps ErrUnreachable when the
+// expression cannot roll a total in the range.
+func (m *Manager) RollBetween(expr string, lo, hi int, mods ...int) (int, Result, error) {
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	exp, err := m.cache.lookup(expr, true)
+	if err != nil {
+		return 0, Result{}, fmt.Errorf("roll %q %v in [%d, %d] failed: %w", expr, mods, lo, hi, err)
+	}
+	if err := reachable(exp, lo, hi, mods); err != nil {
+		return 0, Result{}, fmt.Errorf("roll %q %v in [%d, %d] failed: %w", expr, mods, lo, hi, err)
+	}
+	trail, err := m.searchTrail(expr, lo, hi, mods)
+	if err != nil {
+		return 0, Result{}, fmt.Errorf("roll %q %v in [%d, %d] failed: %w", expr, mods, lo, hi, err)
+	}
+	intrpr, res, err := m.replayTrail(expr, trail, mods)
+	if err != nil {
+		return 0, Result{}, fmt.Errorf("roll %q %v in [%d, %d] failed: %w", expr, mods, lo, hi, err)
+	}
+	return intrpr.sum, Result{dice: res.dice, raw: res.raw, codes: res.codes, explosions: res.explosions}, nil
+}
+
+// RollBetween uses the default manager to roll an expression within [lo, hi].
+func RollBetween(expr string, lo, hi int, mods ...int) (int, Result, error) {
+	return defaultManager.RollBetween(expr, lo, hi, mods...)
+}
+
+// Force returns dice that roll exactly total under the expression and its modifiers.
+// It is RollBetween(expr, total, total, mods...).
+func (m *Manager) Force(expr string, total int, mods ...int) (Result, error) {
+	_, res, err := m.RollBetween(expr, total, total, mods...)
+	return res, err
+}
+
+// Force uses the default manager to find dice rolling exactly total.
+func Force(expr string, total int, mods ...int) (Result, error) {
+	return defaultManager.Force(expr, total, mods...)
+}
+
+// reachable fails a range the expression cannot roll. The range of the expression and,
+// when it can be computed, its distribution rule out most impossible totals before any
+// search; concat expressions adjust their dice with the modifiers and are left to it.
+func reachable(exp *expression, lo, hi int, mods []int) error {
+	if _, ok := exp.concatGroup(); ok {
+		return nil
+	}
+	shift := sum(mods...)
+	ex, err := Inspect(exp.code)
+	if err != nil {
+		return nil
+	}
+	if ex.Max+shift < lo || ex.Min+shift > hi {
+		return fmt.Errorf("%w: %q rolls %d to %d", ErrUnreachable, exp.code, ex.Min+shift, ex.Max+shift)
+	}
+	pmf, err := expressionPMF(exp, nil)
+	if err != nil {
+		return nil
+	}
+	for v, p := range pmf {
+		if p > 0 && v+shift >= lo && v+shift <= hi {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q never rolls a total in [%d, %d]", ErrUnreachable, exp.code, lo, hi)
+}
+
+// trail is the sequence of die faces drawn by one roll, with the faces of each die.
+type trail struct {
+	draws []int
+	faces []int
+	miss  int
+}
+
+// trailRoller replays a script of faces, clamped to each die, and draws from next once
+// the script is used up. It records every face it returns and belongs to one roll.
+type trailRoller struct {
+	script []int
+	next   roller
+	trail  trail
+}
+
+func (r *trailRoller) roll(d die) int {
+	var v int
+	if i := len(r.trail.draws); i < len(r.script) {
+		v = setBounds(r.script[i], 1, d.faces)
+	} else {
+		v = r.next.roll(d)
+	}
+	r.trail.draws = append(r.trail.draws, v)
+	r.trail.faces = append(r.trail.faces, d.faces)
+	return v
+}
+
+// searchTrail looks for the dice of a roll with a total in [lo, hi]. The search stream
+// depends only on the draw that seeds it, not on the seed of the Manager, so a Manager
+// replaying the draw searches the same stream.
+func (m *Manager) searchTrail(expr string, lo, hi int, dms []int) (trail, error) {
+	search := newManager(&sourceRoller{src: newDerivedSource(fmt.Sprintf("%s%d", reverseLabel, m.draw(continuousResolution)))})
+	attempt := func(script []int) (trail, bool) {
+		tr := &trailRoller{script: script, next: search.roller}
+		probe := newManager(tr)
+		probe.cache, probe.budget = m.cache, m.budget
+		intrpr, _, err := probe.evaluate(expr, nil, dms)
+		if err != nil {
+			return trail{}, false
+		}
+		tr.trail.miss = max(lo-intrpr.sum, intrpr.sum-hi, 0)
+		return tr.trail, true
+	}
+	var best trail
+	found := false
+	for i := 0; i < maxReverseAttempts && (!found || best.miss > 0); i++ {
+		var script []int
+		if i >= reverseSamples && found && len(best.draws) > 0 {
+			script = mutate(best, search)
+		}
+		cand, ok := attempt(script)
+		if !ok {
+			continue
+		}
+		// Plain samples must improve; adjusted dice may also move sideways, so the
+		// search can cross totals that several dice reach equally.
+		if !found || cand.miss < best.miss || (script != nil && cand.miss == best.miss) {
+			best, found = cand, true
+		}
+	}
+	if !found || best.miss > 0 {
+		return trail{}, fmt.Errorf("no dice found after %d attempts", maxReverseAttempts)
+	}
+	return best, nil
+}
+
+// mutate returns the faces of t with one die turned to another face.
+func mutate(t trail, search *Manager) []int {
+	script := append([]int(nil), t.draws...)
+	i := search.draw(len(script)) - 1
+	script[i] = search.draw(t.faces[i])
+	return script
+}
+
+// replayTrail rolls the expression on the faces of t as a normal roll of the Manager:
+// it is logged with the seed of the Manager and becomes the last result. The faces are
+// not recorded; a replay finds them again from the draw seeding the search.
+func (m *Manager) replayTrail(expr string, t trail, dms []int) (interpretation, result, error) {
+	replay := newManager(&sourceRoller{src: NewReplaySource(t.draws...)})
+	replay.cache, replay.budget = m.cache, m.budget
+	ctx := context.Background()
+	rs := newRollState(ctx, m.budgetFor(ctx), m.interpreter, nil, dms)
+	intrpr, err := replay.evaluateState(rs, expr)
+	if l := m.logger.Load(); l != nil {
+		(*l).LogRoll(m.record(rs, expr, intrpr, err))
+	}
+	if err != nil {
+		return interpretation{}, result{}, err
+	}
+	m.last.Store(&rs.result)
+	return intrpr, rs.result, nil
+}
+
+// draw returns a face of a die with the given faces from the Manager's stream,
+// through a recording in progress but without logging a roll.
+func (m *Manager) draw(faces int) int {
+	var r roller = m.roller
+	if rec := m.recorder.Load(); rec != nil {
+		r = rec
+	}
+	return r.roll(newDie(faces))
+}
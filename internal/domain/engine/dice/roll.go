@@ -1,11 +1,48 @@
 package dice
 
 // basicRoll rolls every die in the dicepool and returns a Result.
+// Exploding dice add their extra dice right after the originating die,
+// compounding dice fold the extra rolls into a single value.
+// Every raw value has a code: the symbol of the face rolled, or "" for plain dice.
 func basicRoll(r roller, dp dicepool) result {
-	res := result{}
+	n := len(dp.dice)
+	res := result{
+		dice:  make([]die, 0, n),
+		raw:   make([]int, 0, n),
+		codes: make([]string, 0, n),
+	}
 	for _, d := range dp.dice {
+		face := r.roll(d)
+		value := d.value(face)
+		origin := len(res.raw)
 		res.dice = append(res.dice, d)
-		res.raw = append(res.raw, r.roll(d))
+		res.codes = append(res.codes, d.code(face))
+		extra := 0
+		switch d.explode.mode {
+		case explodeAdd:
+			res.raw = append(res.raw, value)
+			for d.explodes(value) && extra < maxExplosionChain {
+				face = r.roll(d)
+				value = d.value(face)
+				res.dice = append(res.dice, d)
+				res.codes = append(res.codes, d.code(face))
+				res.raw = append(res.raw, value)
+				extra++
+			}
+		case explodeCompound:
+			total := value
+			for d.explodes(value) && extra < maxExplosionChain {
+				value = d.value(r.roll(d))
+				total += value
+				extra++
+			}
+			res.raw = append(res.raw, total)
+		default:
+			res.raw = append(res.raw, value)
+		}
+		if extra > 0 {
+			res.explosions = append(res.explosions, Explosion{Index: origin, Extra: extra})
+		}
 	}
 	return res
 }
@@ -1,7 +1,9 @@
 package dice
 
 import (
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -11,23 +13,77 @@ type roller interface {
 }
 
 // randRoller is the default implementation using math/rand.
+// mu serialises draws, since math/rand generators are not safe for concurrent use.
 type randRoller struct {
-	rng *rand.Rand
+	mu      sync.Mutex
+	rng     *rand.Rand
+	src     *countingSource
+	seed    string
+	seedInt int64
 }
 
 func (r *randRoller) roll(d die) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.rng.Intn(d.faces) + 1
 }
 
+func (r *randRoller) identity() string {
+	return r.seed
+}
+
+// identifier is implemented by rollers that can name the seed they were created from.
+type identifier interface {
+	identity() string
+}
+
 // newRoller creates a roller seeded either by the given string or a random seed.
 func newRoller(seed string) roller {
 	seedInt := randomSeed()
 	if seed != "" {
 		seedInt = stringToInt64(seed)
 	}
-	r := randRoller{}
-	r.rng = rand.New(rand.NewSource(seedInt))
-	return &r
+	identity := seed
+	if seed == "" {
+		identity = fmt.Sprintf("random:%d", seedInt)
+	}
+	return newRandRoller(identity, seedInt, 0)
+}
+
+// newRandRoller creates a math/rand roller and fast-forwards it past draws values.
+func newRandRoller(identity string, seedInt int64, draws uint64) *randRoller {
+	src := &countingSource{src: rand.NewSource(seedInt).(rand.Source64)}
+	for range draws {
+		src.Uint64()
+	}
+	return &randRoller{
+		rng:     rand.New(src),
+		src:     src,
+		seed:    identity,
+		seedInt: seedInt,
+	}
+}
+
+// countingSource wraps a math/rand source and counts the values drawn from it,
+// so the position of a seeded stream can be saved and restored.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func (c *countingSource) Int63() int64 {
+	c.draws++
+	return c.src.Int63()
+}
+
+func (c *countingSource) Uint64() uint64 {
+	c.draws++
+	return c.src.Uint64()
+}
+
+func (c *countingSource) Seed(seed int64) {
+	c.draws = 0
+	c.src.Seed(seed)
 }
 
 // stringToInt64 converts a string into an int64 seed for deterministic randomness.
@@ -49,4 +105,4 @@ func stringToInt64(seed string) int64 {
 // randomSeed returns a seed derived from the current nanosecond timestamp.
 func randomSeed() int64 {
 	return time.Now().UnixNano()
-}
\ No newline at end of file
+}
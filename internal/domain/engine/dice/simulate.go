@@ -0,0 +1,264 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultMaxBins bounds the histogram of a simulation when SimulateOptions.MaxBins is 0.
+const defaultMaxBins = 4096
+
+// RollN rolls the expression n times with the same modifiers and returns every total.
+func (m *Manager) RollN(expr string, n int, mods ...int) ([]int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("roll %q %d times: negative count", expr, n)
+	}
+	totals := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		intrpr, _, err := m.evaluate(expr, nil, mods)
+		if err != nil {
+			return totals, fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
+		}
+		totals = append(totals, intrpr.sum)
+	}
+	return totals, nil
+}
+
+// RollN uses the default manager to roll an expression n times.
+func RollN(expr string, n int, mods ...int) ([]int, error) {
+	return defaultManager.RollN(expr, n, mods...)
+}
+
+// SimulateOptions configures Simulate and SimulateFunc.
+//
+// The trials run on Workers goroutines (1 when 0), worker i rolling on Shard(i) of
+// the parent Manager, so a seeded simulation gives the same Stats on every run for a
+// given number of workers. The parent is Manager when set, otherwise a Manager seeded
+// with Seed (random when empty). MaxBins bounds the histogram, see Stats.
+type SimulateOptions struct {
+	Workers int
+	Seed    string
+	Manager *Manager
+	Vars    Resolver
+	DMs     []int
+	MaxBins int
+}
+
+// Simulate rolls the expression n times and streams the totals into Stats without
+// keeping them. Use it when Distribution is too expensive or cannot see the whole
+// process.
+func Simulate(expr string, n int, opts SimulateOptions) (Stats, error) {
+	if _, err := lookupExpression(expr); err != nil {
+		return Stats{}, fmt.Errorf("simulate %q: %w", expr, err)
+	}
+	stats, err := SimulateFunc(n, opts, func(m *Manager) (int, error) {
+		intrpr, _, err := m.evaluate(expr, opts.Vars, opts.DMs)
+		return intrpr.sum, err
+	})
+	if err != nil {
+		return stats, fmt.Errorf("simulate %q: %w", expr, err)
+	}
+	return stats, nil
+}
+
+// SimulateFunc runs trial n times and streams its outcomes into Stats. Every call
+// gets the Manager of its worker, so trial can drive anything that rolls through a
+// Manager, e.g. a cascade through tables.Collection.RollCascade mapped to an int.
+// The first error stops all workers and is returned with the trial number.
+func SimulateFunc(n int, opts SimulateOptions, trial func(m *Manager) (int, error)) (Stats, error) {
+	if n < 0 {
+		return Stats{}, fmt.Errorf("negative trial count %d", n)
+	}
+	if trial == nil {
+		return Stats{}, errors.New("no trial function")
+	}
+	parent := opts.Manager
+	if parent == nil {
+		var err error
+		if parent, err = New(opts.Seed); err != nil {
+			return Stats{}, err
+		}
+	}
+	workers := max(opts.Workers, 1)
+	maxBins := opts.MaxBins
+	if maxBins <= 0 {
+		maxBins = defaultMaxBins
+	}
+
+	partial := make([]Stats, workers)
+	errs := make([]error, workers)
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		first, last := n*w/workers, n*(w+1)/workers
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := parent.Shard(w)
+			s := newStats(maxBins)
+			for i := first; i < last && !stop.Load(); i++ {
+				v, err := trial(m)
+				if err != nil {
+					errs[w] = fmt.Errorf("trial %d: %w", i+1, err)
+					stop.Store(true)
+					return
+				}
+				s.add(v)
+			}
+			partial[w] = s
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return Stats{}, err
+	}
+	total := newStats(maxBins)
+	for _, s := range partial {
+		total.merge(s)
+	}
+	return total, nil
+}
+
+// Stats summarises a stream of outcomes.
+//
+// Mean and variance are kept with Welford's running algorithm. Histogram counts the
+// outcomes in bins of BinWidth values keyed by the lowest value of the bin; the width
+// starts at 1, which makes the histogram and the percentiles exact, and doubles
+// whenever more than MaxBins bins would be needed, so memory stays bounded and a
+// percentile is off by less than BinWidth.
+type Stats struct {
+	N         int
+	Min       int
+	Max       int
+	Histogram map[int]int
+	BinWidth  int
+
+	mean    float64
+	m2      float64
+	maxBins int
+}
+
+func newStats(maxBins int) Stats {
+	return Stats{Histogram: make(map[int]int), BinWidth: 1, maxBins: maxBins}
+}
+
+// Mean returns the average outcome.
+func (s Stats) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the population variance of the outcomes.
+func (s Stats) Variance() float64 {
+	if s.N == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.N)
+}
+
+// StdDev returns the population standard deviation of the outcomes.
+func (s Stats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Probability returns the share of outcomes in the bin holding v: the exact
+// probability of v while BinWidth is 1.
+func (s Stats) Probability(v int) float64 {
+	if s.N == 0 {
+		return 0
+	}
+	return float64(s.Histogram[s.bin(v)]) / float64(s.N)
+}
+
+// Percentile returns the smallest outcome bin with at least p (0..1) of the outcomes
+// at or below it, clamped to [Min, Max]. Percentile(0.5) is the median.
+func (s Stats) Percentile(p float64) int {
+	if s.N == 0 {
+		return 0
+	}
+	p = min(max(p, 0), 1)
+	need := int(math.Ceil(p * float64(s.N)))
+	bins := slices.Sorted(maps.Keys(s.Histogram))
+	seen := 0
+	for _, b := range bins {
+		seen += s.Histogram[b]
+		if seen >= need {
+			return min(max(b, s.Min), s.Max)
+		}
+	}
+	return s.Max
+}
+
+// String renders a one-line summary.
+func (s Stats) String() string {
+	return fmt.Sprintf("n=%d mean=%.3f sd=%.3f min=%d p50=%d max=%d",
+		s.N, s.Mean(), s.StdDev(), s.Min, s.Percentile(0.5), s.Max)
+}
+
+func (s *Stats) add(v int) {
+	if s.N == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.N == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.N++
+	delta := float64(v) - s.mean
+	s.mean += delta / float64(s.N)
+	s.m2 += delta * (float64(v) - s.mean)
+	s.Histogram[s.bin(v)]++
+	s.compact()
+}
+
+// merge folds o into s with the parallel form of Welford's algorithm.
+func (s *Stats) merge(o Stats) {
+	if o.N == 0 {
+		return
+	}
+	if s.N == 0 {
+		s.Min, s.Max = o.Min, o.Max
+	} else {
+		s.Min, s.Max = min(s.Min, o.Min), max(s.Max, o.Max)
+	}
+	n := s.N + o.N
+	delta := o.mean - s.mean
+	s.m2 += o.m2 + delta*delta*float64(s.N)*float64(o.N)/float64(n)
+	s.mean += delta * float64(o.N) / float64(n)
+	s.N = n
+	for s.BinWidth < o.BinWidth {
+		s.widen()
+	}
+	for b, c := range o.Histogram {
+		s.Histogram[s.bin(b)] += c
+	}
+	s.compact()
+}
+
+// bin returns the key of the bin holding v.
+func (s *Stats) bin(v int) int {
+	b := v / s.BinWidth
+	if v%s.BinWidth < 0 {
+		b--
+	}
+	return b * s.BinWidth
+}
+
+func (s *Stats) compact() {
+	for len(s.Histogram) > s.maxBins {
+		s.widen()
+	}
+}
+
+// widen doubles the bin width and folds the histogram into the wider bins.
+func (s *Stats) widen() {
+	s.BinWidth *= 2
+	wide := make(map[int]int, len(s.Histogram)/2+1)
+	for b, c := range s.Histogram {
+		wide[s.bin(b)] += c
+	}
+	s.Histogram = wide
+}
@@ -0,0 +1,167 @@
+package dice
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// Snapshot is the saved position of a Manager's random stream.
+// It is plain data and can be stored as JSON; Restore turns it back into a Manager
+// that continues exactly where the original stopped.
+type Snapshot struct {
+	Kind      string `json:"kind"`
+	Seed      string `json:"seed"`
+	SeedValue int64  `json:"seed_value,omitempty"`
+	Draws     uint64 `json:"draws,omitempty"`
+	State     []byte `json:"state,omitempty"`
+	Values    []int  `json:"values,omitempty"`
+	Position  int    `json:"position,omitempty"`
+}
+
+// Snapshot saves the current position of the random stream.
+// Seeded managers store the seed and the number of values drawn, PCG and ChaCha8
+// sources store their full generator state, scripted and replay sources store their
+// values and position. Crypto and foreign sources cannot be saved.
+func (m *Manager) Snapshot() (Snapshot, error) {
+	switch r := m.roller.(type) {
+	case *randRoller:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return Snapshot{Kind: sourceSeeded, Seed: r.seed, SeedValue: r.seedInt, Draws: r.src.draws}, nil
+	case *sourceRoller:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		switch src := r.src.(type) {
+		case *randSource:
+			if src.gen == nil {
+				return Snapshot{}, fmt.Errorf("%s source cannot be saved", src.kind)
+			}
+			state, err := src.gen.MarshalBinary()
+			if err != nil {
+				return Snapshot{}, fmt.Errorf("failed to marshal %s state: %w", src.kind, err)
+			}
+			return Snapshot{Kind: src.kind, Seed: src.name, State: state}, nil
+		case *ScriptedSource:
+			src.mu.Lock()
+			defer src.mu.Unlock()
+			return Snapshot{Kind: sourceScripted, Seed: sourceScripted, Values: append([]int(nil), src.values...), Position: src.next}, nil
+		case *ReplaySource:
+			src.mu.Lock()
+			defer src.mu.Unlock()
+			return Snapshot{Kind: sourceReplay, Seed: sourceReplay, Values: append([]int(nil), src.values...), Position: src.next}, nil
+		default:
+			return Snapshot{}, fmt.Errorf("source %T cannot be saved", r.src)
+		}
+	default:
+		return Snapshot{}, fmt.Errorf("roller %T cannot be saved", m.roller)
+	}
+}
+
+// Restore creates a Manager that continues the stream saved in the snapshot.
+func Restore(s Snapshot) (*Manager, error) {
+	switch s.Kind {
+	case sourceSeeded:
+		return newManager(newRandRoller(s.Seed, s.SeedValue, s.Draws)), nil
+	case sourcePCG, sourceChaCha8:
+		var gen stateGenerator = &rand.PCG{}
+		if s.Kind == sourceChaCha8 {
+			gen = &rand.ChaCha8{}
+		}
+		if err := gen.UnmarshalBinary(s.State); err != nil {
+			return nil, fmt.Errorf("failed to restore %s state: %w", s.Kind, err)
+		}
+		return newManager(&sourceRoller{src: newStateSource(s.Kind, s.Seed, gen)}), nil
+	case sourceScripted:
+		src := NewScriptedSource(s.Values...)
+		src.next = s.Position
+		return newManager(&sourceRoller{src: src}), nil
+	case sourceReplay:
+		src := NewReplaySource(s.Values...)
+		src.next = s.Position
+		return newManager(&sourceRoller{src: src}), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot kind %q", s.Kind)
+	}
+}
+
+// recorder captures every die value drawn by the wrapped roller.
+// The draw and the append happen under one lock so the recording keeps stream order.
+type recorder struct {
+	roller
+	mu     sync.Mutex
+	values []int
+}
+
+func (r *recorder) roll(d die) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.roller.roll(d)
+	r.values = append(r.values, v)
+	return v
+}
+
+// StartRecording starts capturing every die value the Manager draws, including
+// the extra rolls of exploding dice. A recording already in progress is discarded.
+func (m *Manager) StartRecording() {
+	m.recorder.Store(&recorder{roller: m.roller})
+}
+
+// StopRecording stops capturing and returns the recorded values.
+// Feed them to NewReplaySource to replay the session exactly.
+func (m *Manager) StopRecording() []int {
+	rec := m.recorder.Swap(nil)
+	if rec == nil {
+		return nil
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.values
+}
+
+// ReplaySource returns recorded die values in order. Unlike ScriptedSource it does
+// not start over: rolling past the end of the recording fails the roll.
+type ReplaySource struct {
+	mu     sync.Mutex
+	values []int
+	next   int
+	err    error
+}
+
+// NewReplaySource creates a ReplaySource from values captured with StopRecording.
+func NewReplaySource(values ...int) *ReplaySource {
+	return &ReplaySource{values: append([]int(nil), values...)}
+}
+
+// RollDie returns the next recorded value.
+func (s *ReplaySource) RollDie(faces int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= len(s.values) {
+		if s.err == nil {
+			s.err = fmt.Errorf("replay exhausted after %d values", len(s.values))
+		}
+		return 1
+	}
+	v := s.values[s.next]
+	s.next++
+	return v
+}
+
+// Err reports whether the replay ran out of recorded values.
+func (s *ReplaySource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Remaining returns how many recorded values have not been used yet.
+func (s *ReplaySource) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values) - s.next
+}
+
+func (s *ReplaySource) identity() string {
+	return sourceReplay
+}
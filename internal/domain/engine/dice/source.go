@@ -0,0 +1,159 @@
+package dice
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	sourceSeeded   = "seeded"
+	sourcePCG      = "pcg"
+	sourceChaCha8  = "chacha8"
+	sourceCrypto   = "crypto"
+	sourceScripted = "scripted"
+	sourceReplay   = "replay"
+)
+
+// Source produces the value of a single die roll.
+// RollDie must return a value in [1, faces]. A Manager serialises calls to its Source.
+// Sources that can run dry may also implement Err() error; a non-nil error fails the roll.
+type Source interface {
+	RollDie(faces int) int
+}
+
+// Option configures a Manager created with New.
+type Option func(*Manager) error
+
+// WithSource makes the Manager roll every die from src instead of the seeded default.
+func WithSource(src Source) Option {
+	return func(m *Manager) error {
+		if src == nil {
+			return fmt.Errorf("nil source provided")
+		}
+		m.roller = &sourceRoller{src: src}
+		return nil
+	}
+}
+
+// sourceRoller adapts a public Source to the internal roller interface.
+// mu serialises calls to the Source.
+type sourceRoller struct {
+	mu  sync.Mutex
+	src Source
+}
+
+func (r *sourceRoller) err() error {
+	if f, ok := r.src.(interface{ Err() error }); ok {
+		return f.Err()
+	}
+	return nil
+}
+
+func (r *sourceRoller) roll(d die) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return setBounds(r.src.RollDie(d.faces), 1, d.faces)
+}
+
+func (r *sourceRoller) identity() string {
+	if id, ok := r.src.(identifier); ok {
+		return id.identity()
+	}
+	return fmt.Sprintf("source:%T", r.src)
+}
+
+// randSource rolls from any math/rand/v2 generator.
+// gen is the underlying generator when its state can be saved (PCG, ChaCha8).
+type randSource struct {
+	rng  *rand.Rand
+	gen  stateGenerator
+	kind string
+	name string
+}
+
+// stateGenerator is a math/rand/v2 generator whose full state can be marshaled.
+type stateGenerator interface {
+	rand.Source
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+}
+
+func newStateSource(kind, name string, gen stateGenerator) *randSource {
+	return &randSource{rng: rand.New(gen), gen: gen, kind: kind, name: name}
+}
+
+func (s *randSource) RollDie(faces int) int {
+	return s.rng.IntN(faces) + 1
+}
+
+func (s *randSource) identity() string {
+	return s.name
+}
+
+// NewPCGSource returns a Source backed by the math/rand/v2 PCG generator.
+func NewPCGSource(seed1, seed2 uint64) Source {
+	return newStateSource(sourcePCG, fmt.Sprintf("pcg:%d:%d", seed1, seed2), rand.NewPCG(seed1, seed2))
+}
+
+// NewChaCha8Source returns a Source backed by the math/rand/v2 ChaCha8 generator.
+func NewChaCha8Source(seed [32]byte) Source {
+	return newStateSource(sourceChaCha8, fmt.Sprintf("chacha8:%x", seed), rand.NewChaCha8(seed))
+}
+
+// cryptoUint64 feeds crypto/rand into math/rand/v2 so faces stay unbiased.
+type cryptoUint64 struct{}
+
+func (cryptoUint64) Uint64() uint64 {
+	b := [8]byte{}
+	_, _ = crand.Read(b[:]) // crypto/rand.Read never returns an error
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// NewCryptoSource returns a non-reproducible Source backed by crypto/rand.
+func NewCryptoSource() Source {
+	return &randSource{
+		rng:  rand.New(cryptoUint64{}),
+		kind: sourceCrypto,
+		name: sourceCrypto,
+	}
+}
+
+// ScriptedSource returns predefined die values in order, starting over when the
+// script is exhausted. Values outside [1, faces] are clamped by the Manager.
+// It is meant for tests and for replaying known rolls.
+type ScriptedSource struct {
+	mu     sync.Mutex
+	values []int
+	next   int
+}
+
+// NewScriptedSource creates a ScriptedSource. Without values every die rolls 1.
+func NewScriptedSource(values ...int) *ScriptedSource {
+	return &ScriptedSource{values: append([]int(nil), values...)}
+}
+
+// RollDie returns the next scripted value.
+func (s *ScriptedSource) RollDie(faces int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.values) == 0 {
+		return 1
+	}
+	v := s.values[s.next%len(s.values)]
+	s.next++
+	return v
+}
+
+// Used returns how many values were consumed so far.
+func (s *ScriptedSource) Used() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
+
+func (s *ScriptedSource) identity() string {
+	return sourceScripted
+}
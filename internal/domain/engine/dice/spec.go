@@ -2,7 +2,10 @@
 // modifier chaining, and support for common RPG mechanics (D66, Flux, etc.).
 package dice
 
-import "sync"
+import (
+	"fmt"
+	"sync/atomic"
+)
 
 var defaultRoller roller
 var defaultManager *Manager
@@ -13,28 +16,53 @@ func init() {
 }
 
 // Roller is the interface that wraps the Roll method.
-// It defines how a single die is rolled.
+// It is implemented by Manager; code that only needs to roll expressions should
+// accept a Roller so it can be driven by a Manager built on any Source, or by a fake.
 type Roller interface {
-	roll(die) int
+	Roll(expr string, mods ...int) (int, error)
 }
 
 // Manager coordinates dice rolling, expression caching, and result interpretation.
+// It is safe for concurrent use and holds no lock while parsing and interpreting;
+// only the draws from its random stream are serialised. Goroutines sharing a Manager
+// interleave their draws, so use Shard to give every goroutine its own deterministic stream.
 type Manager struct {
-	roller    roller
-	rollState *rollState
-	mu        sync.Mutex
+	roller      roller
+	interpreter interpreter
+	cache       *Cache
+	budget      Budget
+	logger      atomic.Pointer[Logger]
+	recorder    atomic.Pointer[recorder]
+	last        atomic.Pointer[result]
 }
 
-// New creates a new Manager with the given Roller.
-// If roller is nil, the default (math/rand with random seed) is used.
-func New(seed string) (*Manager, error) {
-	return newManager(newRoller(seed)), nil
+// New creates a new Manager seeded from the given string.
+// An empty seed produces a random seed. Options may replace the random source,
+// e.g. New("", WithSource(NewPCGSource(1, 2))).
+func New(seed string, opts ...Option) (*Manager, error) {
+	m := newManager(newRoller(seed))
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, fmt.Errorf("failed to create manager: %w", err)
+		}
+	}
+	return m, nil
 }
 
 // Result contains the dice that were rolled and their raw values (order preserved).
 type Result struct {
-	dice []die
-	raw  []int
+	dice       []die
+	raw        []int
+	codes      []string
+	explosions []Explosion
+}
+
+// Explosion reports a die that exploded during a roll.
+// Index is the position of the originating die in Raw, Extra is the number of
+// additional dice it produced (or extra rolls folded in, for compounding dice).
+type Explosion struct {
+	Index int `json:"index"`
+	Extra int `json:"extra"`
 }
 
 // Dice returns a copy of the dice that were rolled.
@@ -46,3 +74,20 @@ func (r Result) Dice() []die {
 func (r Result) Raw() []int {
 	return append([]int(nil), r.raw...)
 }
+
+// Codes returns the face symbols of the rolled dice, aligned with Raw.
+// Dice without symbols (plain numbered dice) report "". It returns nil when no die
+// in the roll has symbols.
+func (r Result) Codes() []string {
+	for _, c := range r.codes {
+		if c != "" {
+			return append([]string(nil), r.codes...)
+		}
+	}
+	return nil
+}
+
+// Explosions returns a copy of the explosions that happened during the roll.
+func (r Result) Explosions() []Explosion {
+	return append([]Explosion(nil), r.explosions...)
+}
@@ -1,10 +1,16 @@
 package dice_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Galdoba/cepheus/internal/domain/engine/dice"
@@ -55,10 +61,24 @@ func TestParseValidExpressions(t *testing.T) {
 		{"3d6:/2", "divide each die by 2"},
 		{"3d6:x2", "multiply each die by 2"},
 		{"3d6:*3", "multiply each die by 3 (alternate syntax)"},
-		// {"4d6:dl1+2", "complex: drop lowest then add 2 to sum"},
+		{"4d6:dl1+2", "complex: drop lowest then add 2 to sum"},
 		{"4d6:dl1:2e", "multiple complex modifiers"},
 		{"1d6", "bare die with implicit count 1"},
 		{"d6", "implicit count 1 with d prefix"},
+		{"3d6!", "exploding dice"},
+		{"3d6!!", "compounding dice"},
+		{"3d6!>5", "exploding dice with threshold"},
+		{"2d10!!>9+1", "compounding dice with threshold and additive"},
+		{"4d6!:dl1", "exploding dice with complex modifier"},
+		{"3D6", "concat dice"},
+		{"2D10", "concat d10 pair"},
+		{"3DD6", "destructive dice keep best 2 of 3"},
+		{"2d6+1d3-2", "several dice groups"},
+		{"(1d6+2)*10", "parenthesised product"},
+		{"floor(d100/10)", "rounded division"},
+		{"max(2d6, 7)", "function with spaces"},
+		{"-1d6+10", "unary minus"},
+		{"(3d6+2):/2", "modifiers on a parenthesised group"},
 	}
 
 	for _, tt := range tests {
@@ -85,6 +105,19 @@ func TestParseInvalidExpressions(t *testing.T) {
 		{"3d6:dl0", "invalid dl count"},
 		{"3d6:dh-1", "invalid dh count"},
 		{"3d6:/0", "invalid divisor: 0"},
+		{"DD6", "destructive dice need at least 2 dice"},
+		{"1d1!", "explosion threshold 1 out of range"},
+		{"3d6!>7", "explosion threshold 7 out of range"},
+		{"3d6!>", "invalid explosion threshold"},
+		{"3d6!?", "unexpected characters"},
+		{"2d6+", "unexpected end of expression"},
+		{"(2d6", "missing closing parenthesis"},
+		{"max 3", "missing '(' after max"},
+		{"abs(1d6, 2)", "wrong number of arguments"},
+		{"2d6 3", "unexpected characters"},
+		{"(2d6+1d6):dl1", "single added dice group"},
+		{"(2d6*2):dl1", "cannot be applied"},
+		{"7", "expression has no dice"},
 		// {"3d6:dl5", "cannot drop 5 dice from pool of 3"},
 	}
 
@@ -204,6 +237,1106 @@ func TestRollWithInlineMods(t *testing.T) {
 	}
 }
 
+// ----------------------------------------------------------------------
+// Exploding Dice Tests
+
+func TestExplodingDice(t *testing.T) {
+	m := newSeededManager(t, "explode")
+
+	for i := 0; i < 200; i++ {
+		if _, err := m.Roll("4d6!>5"); err != nil {
+			t.Fatalf("Roll failed: %v", err)
+		}
+		res := m.Result()
+		raw := res.Raw()
+		extra := 0
+		for _, ex := range res.Explosions() {
+			if raw[ex.Index] < 5 {
+				t.Errorf("die at %d exploded with value %d below threshold 5", ex.Index, raw[ex.Index])
+			}
+			extra += ex.Extra
+		}
+		if len(raw) != 4+extra {
+			t.Errorf("raw length = %d, want %d (4 dice + %d extra)", len(raw), 4+extra, extra)
+		}
+		if len(res.Dice()) != len(raw) {
+			t.Errorf("dice length = %d, raw length = %d", len(res.Dice()), len(raw))
+		}
+	}
+}
+
+func TestCompoundingDice(t *testing.T) {
+	m := newSeededManager(t, "compound")
+
+	exploded := false
+	for i := 0; i < 200; i++ {
+		if _, err := m.Roll("3d6!!"); err != nil {
+			t.Fatalf("Roll failed: %v", err)
+		}
+		res := m.Result()
+		raw := res.Raw()
+		if len(raw) != 3 {
+			t.Fatalf("compounding dice changed pool size: %v", raw)
+		}
+		for _, ex := range res.Explosions() {
+			exploded = true
+			if raw[ex.Index] < 6*ex.Extra+1 {
+				t.Errorf("compounded value %d too low for %d extra rolls", raw[ex.Index], ex.Extra)
+			}
+		}
+	}
+	if !exploded {
+		t.Errorf("no die exploded in 200 rolls of 3d6!!")
+	}
+}
+
+// ----------------------------------------------------------------------
+// Concat and Destructive Dice Tests
+
+func TestConcatDice(t *testing.T) {
+	m := newSeededManager(t, "concat")
+
+	for i := 0; i < 100; i++ {
+		code, err := m.RollCode("3D6")
+		if err != nil {
+			t.Fatalf("RollCode failed: %v", err)
+		}
+		raw := m.Result().Raw()
+		want := fmt.Sprintf("%d%d%d", raw[0], raw[1], raw[2])
+		if code != want {
+			t.Errorf("RollCode(3D6) = %q, dice %v, want %q", code, raw, want)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		code, err := m.RollCode("2D10")
+		if err != nil {
+			t.Fatalf("RollCode failed: %v", err)
+		}
+		if len(code) != 2 {
+			t.Errorf("RollCode(2D10) = %q, want two digits", code)
+		}
+	}
+
+	m = newSeededManager(t, "concat-mods")
+	val, err := m.Roll("2D6", 3, -6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := m.Result().Raw()
+	want := min(raw[0]+3, 9)*10 + max(raw[1]-6, 0)
+	if val != want {
+		t.Errorf("Roll(2D6, 3, -6) = %d with dice %v, want %d", val, raw, want)
+	}
+
+	// Nothing applies to the joined number: "3D6+1" on 3,6,4 would read 365.
+	for _, tt := range []struct{ expr, errSubstr string }{
+		{"3D6+1", "cannot apply +1"},
+		{"3D6-1", "cannot apply -1"},
+		{"2D6:/2", "cannot apply"},
+		{"2D6:*10", "cannot apply"},
+		{"3D6*2", "must be the whole expression"},
+		{"2D6+1d6", "must be the whole expression"},
+	} {
+		if err := dice.ValidateExpression(tt.expr); err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+			t.Errorf("ValidateExpression(%q) = %v, want %q", tt.expr, err, tt.errSubstr)
+		}
+	}
+	code, err := newScriptedManager(t, 3, 6, 4).RollCode("3D6", 0, 0, 1)
+	if err != nil || code != "365" {
+		t.Errorf("RollCode(3D6, 0, 0, 1) on 3,6,4 = %q, %v, want 365", code, err)
+	}
+
+	// A bare concat die reads one die per digit: D66 is 2D6, not a 66-sided die.
+	m = newSeededManager(t, "bare D66")
+	for range 500 {
+		v, err := m.Roll("D66")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 11 || v > 66 || v%10 < 1 || v%10 > 6 || v/10 > 6 {
+			t.Fatalf("Roll(D66) = %d, want two digits 1-6", v)
+		}
+	}
+	if ex, err := dice.Inspect("D666"); err != nil || ex.Groups[0].Count != 3 || ex.Groups[0].Faces != 6 {
+		t.Errorf("Inspect(D666) = %+v, %v, want 3D6", ex.Groups, err)
+	}
+	for _, expr := range []string{"D36", "D20"} {
+		if err := dice.ValidateExpression(expr); err == nil || !strings.Contains(err.Error(), "more than 10 faces") {
+			t.Errorf("ValidateExpression(%q) = %v, want a bare concat die error", expr, err)
+		}
+	}
+}
+
+func TestDestructiveDice(t *testing.T) {
+	m := newSeededManager(t, "destructive")
+
+	for i := 0; i < 100; i++ {
+		val, err := m.Roll("3DD6")
+		if err != nil {
+			t.Fatalf("Roll failed: %v", err)
+		}
+		raw := m.Result().Raw()
+		if len(raw) != 3 {
+			t.Fatalf("3DD6 rolled %d dice, want 3", len(raw))
+		}
+		want := raw[0] + raw[1] + raw[2] - min(raw[0], raw[1], raw[2])
+		if val != want {
+			t.Errorf("Roll(3DD6) = %d with dice %v, want %d", val, raw, want)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+// Expression Grammar Tests
+
+func TestArithmeticExpressions(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script []int
+		want   int
+	}{
+		{"2d6+1d3-2", []int{3, 5, 2}, 8},
+		{"(1d6+2)*10", []int{4}, 60},
+		{"1d6*(1d6-1)", []int{3, 5}, 12},
+		{"10-1d6", []int{4}, 6},
+		{"-1d6+3", []int{5}, -2},
+		{"d100/10", []int{57}, 5},
+		{"floor(d100/10)", []int{57}, 5},
+		{"ceil(d100/10)", []int{57}, 6},
+		{"round(d100/10)", []int{55}, 6},
+		{"round(d100/10)", []int{54}, 5},
+		{"max(2d6, 7)", []int{1, 2}, 7},
+		{"max(2d6, 7)", []int{6, 5}, 11},
+		{"min(1d6, 1d6, 1d6)", []int{4, 2, 6}, 2},
+		{"abs(1d6-1d6)", []int{2, 5}, 3},
+		{"4d6:dl1+2", []int{1, 4, 5, 6}, 17},
+		{"(3d6+2):/2", []int{2, 3, 4}, 5},
+		{"2d6:dl1+2d6:dh1", []int{2, 5, 3, 6}, 8},
+		{"(3d6:x2)+1", []int{6, 6, 6}, 37}, // the constant is added after the chain
+		{"d100:/10+1", []int{100}, 11},     // not (100+1)/10
+		{"3d6+2:/2", []int{6, 6, 6}, 10},   // a constant before the chain is folded in
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			got, err := m.Roll(tt.expr)
+			if err != nil {
+				t.Fatalf("Roll(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Roll(%q) with %v = %d, want %d", tt.expr, tt.script, got, tt.want)
+			}
+			if raw := m.Result().Raw(); !slices.Equal(raw, tt.script) {
+				t.Errorf("Roll(%q) raw = %v, want %v", tt.expr, raw, tt.script)
+			}
+		})
+	}
+}
+
+func TestLegacyModifierEquivalence(t *testing.T) {
+	// The modifier tail applies to the whole group including additive constants,
+	// so "3d6+2:dl1" and "(3d6+2):dl1" are the same expression.
+	for _, pair := range [][2]string{
+		{"3d6+2:dl1", "(3d6+2):dl1"},
+		{"4d6:dl1+2", "(4d6+2):dl1"},
+		{"3d6-1:/2", "(3d6-1):/2"},
+	} {
+		a, err := dice.Distribution(pair[0])
+		if err != nil {
+			t.Fatalf("Distribution(%q) failed: %v", pair[0], err)
+		}
+		b, err := dice.Distribution(pair[1])
+		if err != nil {
+			t.Fatalf("Distribution(%q) failed: %v", pair[1], err)
+		}
+		if !maps.EqualFunc(a.Table, b.Table, func(x, y float64) bool { return math.Abs(x-y) < 1e-12 }) {
+			t.Errorf("Distribution(%q) differs from Distribution(%q)", pair[0], pair[1])
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"d6 + 2d6", "1d6+2d6"},
+		{"2d6+1", "2d6+1"},
+		{"4d6:+1e:dl1", "4d6:1e:dl1"},
+		{"4d6:dl1:+1e", "4d6:1e:dl1"},
+		{"3d6+2:dl1", "(3d6+2):dl1"},
+		{"3d6!>6", "3d6!"},
+		{"2d10!!>9+1", "2d10!!>9+1"},
+		{"3d6:*3", "3d6:x3"},
+		{"1d6 * (d6 - 1)", "1d6*(1d6-1)"},
+		{"1d6-(1d6-1)", "1d6-(1d6-1)"},
+		{"max( 2d6 , 7 )", "max(2d6,7)"},
+		{"floor(d100/10)", "floor(1d100/10)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := dice.Canonical(tt.expr)
+			if err != nil {
+				t.Fatalf("Canonical(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+			again, err := dice.Canonical(got)
+			if err != nil || again != got {
+				t.Errorf("Canonical(%q) = %q, %v, want a fixed point", got, again, err)
+			}
+		})
+	}
+}
+
+func TestArithmeticDistribution(t *testing.T) {
+	tests := []struct {
+		expr     string
+		min, max int
+		mean     float64
+	}{
+		{"2d6+1d3-2", 1, 13, 7},
+		{"(1d6+2)*10", 30, 80, 55},
+		{"floor(d100/10)", 0, 10, 4.6},
+		{"max(2d6, 7)", 7, 12, 287.0 / 36},
+		{"10-1d6", 4, 9, 6.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pd, err := dice.Distribution(tt.expr)
+			if err != nil {
+				t.Fatalf("Distribution(%q) failed: %v", tt.expr, err)
+			}
+			if pd.Min != tt.min || pd.Max != tt.max || math.Abs(pd.Mean-tt.mean) > 1e-9 {
+				t.Errorf("Distribution(%q) = [%d..%d] mean %v, want [%d..%d] mean %v",
+					tt.expr, pd.Min, pd.Max, pd.Mean, tt.min, tt.max, tt.mean)
+			}
+		})
+	}
+}
+
+// ----------------------------------------------------------------------
+// Variable Tests
+
+func TestRollWithVariables(t *testing.T) {
+	vars := dice.Vars{"SizeDM": 2, "AtmoDM": -1, "Pop": 7}
+	tests := []struct {
+		expr   string
+		script []int
+		want   int
+	}{
+		{"2d6+@SizeDM-@AtmoDM", []int{3, 4}, 10},
+		{"2d6-7+@Pop", []int{1, 6}, 7},
+		{"max(1d6, @Pop)", []int{3}, 7},
+		{"1d6*@SizeDM", []int{5}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			got, err := m.RollWith(tt.expr, vars)
+			if err != nil {
+				t.Fatalf("RollWith(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("RollWith(%q) = %d, want %d", tt.expr, got, tt.want)
+			}
+		})
+	}
+
+	resolver := dice.ResolverFunc(func(name string) (int, bool) {
+		return len(name), name != "Missing"
+	})
+	m := newScriptedManager(t, 2, 2)
+	if got, err := m.RollWith("2d6+@Abc", resolver); err != nil || got != 7 {
+		t.Errorf("RollWith with ResolverFunc = %d, %v, want 7", got, err)
+	}
+	for _, call := range []func() error{
+		func() error { _, err := m.RollWith("2d6+@Missing", resolver); return err },
+		func() error { _, err := m.Roll("2d6+@SizeDM"); return err },
+	} {
+		if err := call(); err == nil || !strings.Contains(err.Error(), "undefined variable") {
+			t.Errorf("rolling with an unresolved variable: error %v, want undefined variable", err)
+		}
+	}
+}
+
+func TestVariablesIntrospection(t *testing.T) {
+	names, err := dice.Variables("2d6+@SizeDM-@AtmoDM+@SizeDM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"SizeDM", "AtmoDM"}) {
+		t.Errorf("Variables = %v, want [SizeDM AtmoDM]", names)
+	}
+	if c, _ := dice.Canonical("2d6 + @Size_DM2"); c != "2d6+@Size_DM2" {
+		t.Errorf("Canonical = %q, want 2d6+@Size_DM2", c)
+	}
+	for _, expr := range []string{"2d6+@", "2d6+@1x"} {
+		if err := dice.ValidateExpression(expr); err == nil || !strings.Contains(err.Error(), "invalid variable name") {
+			t.Errorf("ValidateExpression(%q) = %v, want invalid variable name", expr, err)
+		}
+	}
+
+	pd, err := dice.DistributionWith("2d6+@DM", dice.Vars{"DM": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Min != 5 || pd.Max != 15 {
+		t.Errorf("DistributionWith range = [%d..%d], want [5..15]", pd.Min, pd.Max)
+	}
+	if _, err := dice.Distribution("2d6+@DM"); err == nil {
+		t.Errorf("Distribution without variables succeeded")
+	}
+}
+
+func TestLoggedVariables(t *testing.T) {
+	log := dice.NewMemoryLogger()
+	m := newScriptedManager(t, 3, 4)
+	m.SetLogger(log)
+	if _, err := m.RollWith("2d6+@DM", dice.Vars{"DM": 1, "Unused": 5}); err != nil {
+		t.Fatal(err)
+	}
+	rr := log.Records()[0]
+	if !maps.Equal(rr.Vars, map[string]int{"DM": 1}) {
+		t.Errorf("logged vars = %v, want only DM", rr.Vars)
+	}
+	if want := "2d6+@DM @DM=1 [3 4] -> sum [7] = 8"; rr.String() != want {
+		t.Errorf("record = %q, want %q", rr.String(), want)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Success Pool and Custom Dice Tests
+
+func TestSuccessPools(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script []int
+		want   int
+	}{
+		{"6d6>=5", []int{1, 5, 6, 4, 5, 2}, 3},
+		{"6d6>5", []int{1, 5, 6, 4, 5, 2}, 1},
+		{"4d10<=3", []int{1, 3, 4, 10}, 2},
+		{"3d6=6", []int{6, 6, 1}, 2},
+		{"3d6!>=5", []int{6, 3, 5, 2}, 2},
+		{"6d6>=5+2d6>=6", []int{5, 5, 5, 1, 1, 1, 6, 6}, 5},
+		{"4dF>=1", []int{3, 3, 1, 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			got, err := m.Roll(tt.expr)
+			if err != nil {
+				t.Fatalf("Roll(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Roll(%q) with %v = %d, want %d", tt.expr, tt.script, got, tt.want)
+			}
+		})
+	}
+
+	pd, err := dice.Distribution("6d6>=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Min != 0 || pd.Max != 6 || math.Abs(pd.Mean-2) > 1e-9 {
+		t.Errorf("Distribution(6d6>=5) = [%d..%d] mean %v, want [0..6] mean 2", pd.Min, pd.Max, pd.Mean)
+	}
+}
+
+func TestFudgeDice(t *testing.T) {
+	m := newScriptedManager(t, 1, 2, 3, 3)
+	got, err := m.Roll("4dF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1 {
+		t.Errorf("Roll(4dF) = %d, want 1", got)
+	}
+	res := m.Result()
+	if !slices.Equal(res.Raw(), []int{-1, 0, 1, 1}) {
+		t.Errorf("raw = %v, want [-1 0 1 1]", res.Raw())
+	}
+	if !slices.Equal(res.Codes(), []string{"-", "0", "+", "+"}) {
+		t.Errorf("codes = %v, want [- 0 + +]", res.Codes())
+	}
+
+	pd, err := dice.Distribution("4dF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.Min != -4 || pd.Max != 4 || math.Abs(pd.Mean) > 1e-9 || math.Abs(pd.P(0)-19.0/81) > 1e-9 {
+		t.Errorf("Distribution(4dF) = [%d..%d] mean %v P(0) %v", pd.Min, pd.Max, pd.Mean, pd.P(0))
+	}
+}
+
+func TestCustomDice(t *testing.T) {
+	err := dice.DefineDie("TestBoost",
+		dice.Face{Code: "", Value: 0},
+		dice.Face{Code: "", Value: 0},
+		dice.Face{Code: "S", Value: 1},
+		dice.Face{Code: "SS", Value: 2},
+	)
+	if err != nil {
+		t.Fatalf("DefineDie failed: %v", err)
+	}
+	if err := dice.DefineDie("TestBoost", dice.Face{Code: "x", Value: 1}); err == nil {
+		t.Errorf("redefining a die succeeded")
+	}
+	for _, name := range []string{"", "bad name", "F"} {
+		if err := dice.DefineDie(name, dice.Face{Code: "x", Value: 1}); err == nil {
+			t.Errorf("DefineDie(%q) succeeded", name)
+		}
+	}
+
+	m := newScriptedManager(t, 4, 1, 3)
+	got, err := m.Roll("3d[TestBoost]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 3 {
+		t.Errorf("Roll(3d[TestBoost]) = %d, want 3", got)
+	}
+	if codes := m.Result().Codes(); !slices.Equal(codes, []string{"SS", "", "S"}) {
+		t.Errorf("codes = %q, want [SS  S]", codes)
+	}
+	if c, _ := dice.Canonical("d[TestBoost]>=1 + 2dF"); c != "1d[TestBoost]>=1+2dF" {
+		t.Errorf("Canonical = %q", c)
+	}
+	if codes := newScriptedManager(t).Result().Codes(); codes != nil {
+		t.Errorf("codes of plain dice = %v, want nil", codes)
+	}
+
+	for _, tt := range []struct{ expr, errSubstr string }{
+		{"2d[Nope]", "unknown custom die"},
+		{"2d[TestBoost", "missing ]"},
+		{"2D[TestBoost]", "missing sides"},
+		{"4dF!", "unexpected characters"},
+		{"6d6>=x", "invalid success target"},
+		{"2D6>=5", "cannot count successes"},
+	} {
+		err := dice.ValidateExpression(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+			t.Errorf("ValidateExpression(%q) = %v, want %q", tt.expr, err, tt.errSubstr)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+// Reroll, Keep and Clamp Tests
+
+func TestRerollKeepClamp(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script []int
+		want   int
+	}{
+		{"4d6:r1", []int{1, 3, 1, 1, 5, 2, 6}, 16},
+		{"2d6:r1", []int{1, 4, 1, 1, 3}, 7},
+		{"3d6:ro<3", []int{1, 2, 5, 1, 6}, 12},
+		{"3d6:ro1", []int{1, 4, 4, 1}, 9},
+		{"3d6:r>=5", []int{6, 2, 5, 5, 1, 3}, 6},
+		{"4d6:kh3", []int{1, 4, 5, 6}, 15},
+		{"4d6:kl2", []int{1, 4, 5, 6}, 5},
+		{"3d6:min3", []int{1, 2, 6}, 12},
+		{"3d6:max4", []int{1, 5, 6}, 9},
+		{"4d6:kh3:r1", []int{1, 2, 2, 2, 6}, 10},
+		{"4d6:r1:kh3+2", []int{1, 2, 2, 2, 6}, 12},
+		{"4dF:r-1", []int{1, 2, 3, 3, 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			got, err := m.Roll(tt.expr)
+			if err != nil {
+				t.Fatalf("Roll(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Roll(%q) with %v = %d, want %d", tt.expr, tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRerollPipeline(t *testing.T) {
+	log := dice.NewMemoryLogger()
+	m := newScriptedManager(t, 1, 4, 6, 1, 5, 3)
+	m.SetLogger(log)
+	if _, err := m.Roll("4d6:kh2:r1"); err != nil {
+		t.Fatal(err)
+	}
+	want := "4d6:kh2:r1 [1 4 6 1] -> reroll [5 4 6 3] -> keep highest [5 6] -> sum [11] = 11"
+	if got := log.Lines()[0]; got != want {
+		t.Errorf("transcript = %q, want %q", got, want)
+	}
+	// Rerolls share a priority and keep their written order.
+	if c, _ := dice.Canonical("4d6:kh2:ro<3:max5:r1"); c != "4d6:ro<3:r1:max5:kh2" {
+		t.Errorf("Canonical = %q, want 4d6:ro<3:r1:max5:kh2", c)
+	}
+
+	// Rerolled dice are part of the recording, so a replay reproduces them.
+	src, err := dice.New("reroll-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.StartRecording()
+	first := src.MustRoll("10d6:r<3")
+	values := src.StopRecording()
+	replay, err := dice.New("", dice.WithSource(dice.NewReplaySource(values...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := replay.MustRoll("10d6:r<3"); again != first {
+		t.Errorf("replayed roll = %d, want %d", again, first)
+	}
+	if _, err := replay.Roll("1d6:r<6"); err == nil {
+		t.Errorf("reroll past the end of a replay succeeded")
+	}
+}
+
+func TestRerollDistribution(t *testing.T) {
+	tests := []struct {
+		expr string
+		mean float64
+	}{
+		{"1d6:r1", 4},
+		{"1d6:ro1", 141.0 / 36},
+		{"2d6:min2", 44.0 / 6},
+		{"1d6:max3", 15.0 / 6},
+		{"2d6:r<3:ro6", 2 * (3 + 4 + 5 + 3.5) / 4},
+	}
+	for _, tt := range tests {
+		pd, err := dice.Distribution(tt.expr)
+		if err != nil {
+			t.Fatalf("Distribution(%q) failed: %v", tt.expr, err)
+		}
+		if math.Abs(pd.Mean-tt.mean) > 1e-9 {
+			t.Errorf("Distribution(%q).Mean = %v, want %v", tt.expr, pd.Mean, tt.mean)
+		}
+	}
+
+	kh, err := dice.Distribution("4d6:kh3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, err := dice.Distribution("4d6:dl1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.EqualFunc(kh.Table, dl.Table, func(x, y float64) bool { return math.Abs(x-y) < 1e-12 }) {
+		t.Errorf("4d6:kh3 and 4d6:dl1 distributions differ")
+	}
+	if pd, err := dice.Distribution("3d6:r1:kh2"); err != nil || pd.Min != 4 {
+		t.Errorf("Distribution(3d6:r1:kh2) = min %d, %v, want min 4", pd.Min, err)
+	}
+}
+
+func TestExplodingClampDistribution(t *testing.T) {
+	// Every roll of an explosion chain is a die of its own, so clamps limit each roll
+	// and not the total; explosions follow the face rolled.
+	tests := []struct {
+		expr string
+		mean float64
+		p    map[int]float64
+	}{
+		{"1d6!:max5", 4, map[int]float64{4: 1.0 / 6, 5: 1.0 / 6, 6: 1.0 / 36, 10: 1.0 / 36}},
+		{"2d6!:min3", 9.6, map[int]float64{6: 9.0 / 36, 7: 6.0 / 36, 10: 1.0 / 36, 11: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pd, err := dice.Distribution(tt.expr)
+			if err != nil {
+				t.Fatalf("Distribution(%q) failed: %v", tt.expr, err)
+			}
+			if math.Abs(pd.Mean-tt.mean) > 1e-6 {
+				t.Errorf("mean = %v, want %v", pd.Mean, tt.mean)
+			}
+			for v, want := range tt.p {
+				if got := pd.P(v); math.Abs(got-want) > 1e-9 {
+					t.Errorf("P(%d) = %v, want %v", v, got, want)
+				}
+			}
+			m := newSeededManager(t, "clamped explosions")
+			const n = 20000
+			hits := map[int]int{}
+			for range n {
+				hits[m.MustRoll(tt.expr)]++
+			}
+			for v, want := range tt.p {
+				if got := float64(hits[v]) / n; math.Abs(got-want) > 0.01 {
+					t.Errorf("rolled %d with frequency %.3f, distribution says %.3f", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRerollErrors(t *testing.T) {
+	for _, tt := range []struct{ expr, errSubstr string }{
+		{"1d6:r<=6", "matches every face"},
+		{"3d6:kh0", "invalid keep count"},
+		{"3d6:rx", "invalid reroll test"},
+		{"3d6:ro>=x", "invalid reroll test"},
+		{"3d6:minx", "invalid clamp value"},
+	} {
+		err := dice.ValidateExpression(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+			t.Errorf("ValidateExpression(%q) = %v, want %q", tt.expr, err, tt.errSubstr)
+		}
+	}
+	if _, err := newScriptedManager(t).Roll("3d6:kh4"); err == nil || !strings.Contains(err.Error(), "cannot keep 4 dice") {
+		t.Errorf("Roll(3d6:kh4) error = %v", err)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Check Tests
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    dice.Task
+		script  []int
+		kept    []int
+		total   int
+		effect  int
+		success bool
+	}{
+		{"plain success", dice.Task{DM: 1}, []int{3, 5}, []int{3, 5}, 9, 1, true},
+		{"plain failure", dice.Task{}, []int{3, 4}, []int{3, 4}, 7, -1, false},
+		{"custom target", dice.Task{DM: -1, Target: 10}, []int{6, 5}, []int{6, 5}, 10, 0, true},
+		{"natural 2 fails", dice.Task{DM: 8}, []int{1, 1}, []int{1, 1}, 10, 2, false},
+		{"natural 12 succeeds", dice.Task{DM: -6}, []int{6, 6}, []int{6, 6}, 6, -2, true},
+		{"boon", dice.Task{Boon: true}, []int{2, 6, 4}, []int{4, 6}, 10, 2, true},
+		{"bane", dice.Task{Bane: true}, []int{2, 6, 4}, []int{2, 4}, 6, -2, false},
+		{"boon and bane cancel", dice.Task{Boon: true, Bane: true}, []int{2, 6}, []int{2, 6}, 8, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			cr, err := m.Check(tt.task)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if !slices.Equal(cr.Dice, tt.script) || !slices.Equal(cr.Kept, tt.kept) {
+				t.Errorf("dice = %v keep %v, want %v keep %v", cr.Dice, cr.Kept, tt.script, tt.kept)
+			}
+			if cr.Total != tt.total || cr.Effect != tt.effect || cr.Success != tt.success {
+				t.Errorf("Check = total %d effect %d success %v, want %d %d %v",
+					cr.Total, cr.Effect, cr.Success, tt.total, tt.effect, tt.success)
+			}
+			if cr.Natural != sumInts(tt.kept) {
+				t.Errorf("Natural = %d, want %d", cr.Natural, sumInts(tt.kept))
+			}
+		})
+	}
+}
+
+func sumInts(values []int) int {
+	s := 0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func TestOpposedCheck(t *testing.T) {
+	m := newScriptedManager(t, 4, 4, 5, 5)
+	res, err := m.Opposed(dice.Task{DM: 2}, dice.Task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Active.Effect != 2 || res.Opposing.Effect != 2 || res.Winner != 0 || res.Margin != 0 {
+		t.Errorf("Opposed = %+v, want a tie on Effect 2", res)
+	}
+
+	m = newScriptedManager(t, 6, 5, 1, 3)
+	res, err = m.Opposed(dice.Task{}, dice.Task{DM: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Winner != 1 || res.Margin != 4 {
+		t.Errorf("Opposed winner %d margin %d, want 1 and 4", res.Winner, res.Margin)
+	}
+}
+
+func TestTaskChain(t *testing.T) {
+	m := newScriptedManager(t, 6, 5, 3, 3, 4, 4)
+	results, err := m.Chain(dice.Task{}, dice.Task{}, dice.Task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dms := []int{results[0].DM, results[1].DM, results[2].DM}
+	if !slices.Equal(dms, []int{0, 3, 1}) {
+		t.Errorf("chain DMs = %v, want [0 3 1]", dms)
+	}
+
+	m = newScriptedManager(t, 6, 5, 3, 3)
+	results, err = m.ChainWith(dice.TaskChainDM, dice.Task{}, dice.Task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[1].DM != 2 {
+		t.Errorf("graded chain DM = %d, want 2", results[1].DM)
+	}
+
+	for effect, want := range map[int]int{-7: -3, -6: -3, -5: -2, -2: -2, -1: -1, 0: 1, 1: 2, 5: 2, 6: 3} {
+		if got := dice.TaskChainDM(effect); got != want {
+			t.Errorf("TaskChainDM(%d) = %d, want %d", effect, got, want)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+// Distribution Tests
+
+func TestDistribution(t *testing.T) {
+	const eps = 1e-9
+	tests := []struct {
+		expr     string
+		min, max int
+		mean     float64
+	}{
+		{"2d6", 2, 12, 7},
+		{"3d6+2", 5, 20, 12.5},
+		{"4d6:dl1", 3, 18, 15869.0 / 1296},
+		{"3DD6", 2, 12, 203.0 / 24},
+		{"2d6:2e", 6, 16, 11},
+		{"2d6:+1>>1", 3, 13, 8},
+		{"2d6:x10", 20, 120, 70},
+		{"2D6", 11, 66, 38.5},
+		{"1d6!!", 1, 606, 4.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pd, err := dice.Distribution(tt.expr)
+			if err != nil {
+				t.Fatalf("Distribution(%q) failed: %v", tt.expr, err)
+			}
+			total := 0.0
+			for _, p := range pd.Table {
+				total += p
+			}
+			if math.Abs(total-1) > eps {
+				t.Errorf("probabilities sum to %v, want 1", total)
+			}
+			if pd.Min != tt.min || pd.Max != tt.max {
+				t.Errorf("range = [%d,%d], want [%d,%d]", pd.Min, pd.Max, tt.min, tt.max)
+			}
+			if math.Abs(pd.Mean-tt.mean) > 1e-6 {
+				t.Errorf("mean = %v, want %v", pd.Mean, tt.mean)
+			}
+		})
+	}
+}
+
+func TestDistributionQueries(t *testing.T) {
+	pd, err := dice.Distribution("2d6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pd.P(7)-1.0/6) > 1e-12 {
+		t.Errorf("P(7) = %v, want 1/6", pd.P(7))
+	}
+	if math.Abs(pd.AtLeast(8)-15.0/36) > 1e-12 {
+		t.Errorf("AtLeast(8) = %v, want 15/36", pd.AtLeast(8))
+	}
+	if math.Abs(pd.AtMost(4)-6.0/36) > 1e-12 {
+		t.Errorf("AtMost(4) = %v, want 6/36", pd.AtMost(4))
+	}
+	if p := pd.Percentile(0.5); p != 7 {
+		t.Errorf("Percentile(0.5) = %d, want 7", p)
+	}
+	if math.Abs(pd.Variance-35.0/6) > 1e-9 {
+		t.Errorf("Variance = %v, want 35/6", pd.Variance)
+	}
+
+	if _, err := dice.Distribution("4d6!:dl1"); err == nil {
+		t.Errorf("Distribution of exploding dice with drop should fail")
+	}
+}
+
+// ----------------------------------------------------------------------
+// Logger Tests
+
+func TestMemoryLogger(t *testing.T) {
+	m := newSeededManager(t, "logger")
+	transcript := dice.NewMemoryLogger()
+	m.SetLogger(transcript)
+
+	val, err := m.Roll("4d6+2:dl1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = m.Roll("bad")
+
+	records := transcript.Records()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	rr := records[0]
+	if rr.Expression != "4d6+2:dl1" || rr.Seed != "logger" || rr.Sum != val {
+		t.Errorf("record = %+v, want expression, seed and sum %d", rr, val)
+	}
+	if len(rr.Raw) != 4 || len(rr.DMs) != 1 || rr.DMs[0] != 1 {
+		t.Errorf("record raw %v / dms %v, want 4 dice and DM [1]", rr.Raw, rr.DMs)
+	}
+	wantStages := []string{"drop lowest", "sum", "add to sum"}
+	if len(rr.Stages) != len(wantStages) {
+		t.Fatalf("got %d stages, want %d", len(rr.Stages), len(wantStages))
+	}
+	for i, st := range rr.Stages {
+		if st.Modifier != wantStages[i] {
+			t.Errorf("stage %d = %q, want %q", i, st.Modifier, wantStages[i])
+		}
+	}
+	if records[1].Error == "" {
+		t.Errorf("failed roll was not recorded with an error")
+	}
+	if lines := transcript.Lines(); !strings.HasSuffix(lines[0], fmt.Sprintf("= %d", val)) {
+		t.Errorf("transcript line %q does not end with the sum", lines[0])
+	}
+}
+
+func TestJSONLinesLogger(t *testing.T) {
+	m := newSeededManager(t, "jsonl")
+	sb := &strings.Builder{}
+	logger := dice.NewJSONLinesLogger(sb)
+	m.SetLogger(logger)
+
+	m.MustRoll("2d6")
+	m.MustRoll("1d6!")
+	if err := logger.Err(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), sb.String())
+	}
+	for _, line := range lines {
+		rr := dice.RollRecord{}
+		if err := json.Unmarshal([]byte(line), &rr); err != nil {
+			t.Errorf("line %q is not a roll record: %v", line, err)
+		}
+		if rr.Seed != "jsonl" {
+			t.Errorf("seed = %q, want %q", rr.Seed, "jsonl")
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+// Source Tests
+
+var _ dice.Roller = (*dice.Manager)(nil)
+
+func newScriptedManager(t *testing.T, values ...int) *dice.Manager {
+	t.Helper()
+	m, err := dice.New("", dice.WithSource(dice.NewScriptedSource(values...)))
+	if err != nil {
+		t.Fatalf("dice.New with scripted source failed: %v", err)
+	}
+	return m
+}
+
+func TestScriptedSource(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script []int
+		want   int
+	}{
+		{"4d6:dl1", []int{6, 1, 3, 4}, 13},
+		{"1d6!", []int{6, 6, 3}, 15},
+		{"2d6!!", []int{2, 6, 5}, 13},
+		{"3D6", []int{3, 6, 4}, 364},
+		{"3DD6", []int{2, 5, 4}, 9},
+		{"2d6", []int{9, 0}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			m := newScriptedManager(t, tt.script...)
+			got, err := m.Roll(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Roll(%q) with script %v = %d, want %d", tt.expr, tt.script, got, tt.want)
+			}
+		})
+	}
+
+	m := newScriptedManager(t, 6, 6, 3)
+	m.MustRoll("1d6!")
+	ex := m.Result().Explosions()
+	if len(ex) != 1 || ex[0].Index != 0 || ex[0].Extra != 2 {
+		t.Errorf("Explosions() = %v, want [{0 2}]", ex)
+	}
+	if m.Seed() != "scripted" {
+		t.Errorf("Seed() = %q, want %q", m.Seed(), "scripted")
+	}
+}
+
+func TestSources(t *testing.T) {
+	if _, err := dice.New("", dice.WithSource(nil)); err == nil {
+		t.Errorf("New with nil source should fail")
+	}
+
+	sources := map[string]func() dice.Source{
+		"pcg":     func() dice.Source { return dice.NewPCGSource(1, 2) },
+		"chacha8": func() dice.Source { return dice.NewChaCha8Source([32]byte{42}) },
+	}
+	for name, newSource := range sources {
+		t.Run(name, func(t *testing.T) {
+			m1, _ := dice.New("", dice.WithSource(newSource()))
+			m2, _ := dice.New("", dice.WithSource(newSource()))
+			for i := 0; i < 50; i++ {
+				a, b := m1.MustRoll("3d6"), m2.MustRoll("3d6")
+				if a != b {
+					t.Fatalf("roll %d: same source gave %d and %d", i, a, b)
+				}
+				if a < 3 || a > 18 {
+					t.Fatalf("roll %d: 3d6 = %d out of range", i, a)
+				}
+			}
+		})
+	}
+
+	m, _ := dice.New("", dice.WithSource(dice.NewCryptoSource()))
+	for i := 0; i < 50; i++ {
+		if v := m.MustRoll("1d20"); v < 1 || v > 20 {
+			t.Fatalf("crypto 1d20 = %d out of range", v)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+// Derive Tests
+
+func TestDeriveStable(t *testing.T) {
+	// Pinned values: derived streams are part of the package contract and
+	// must keep reproducing saved campaign seeds across releases.
+	m := newSeededManager(t, "campaign")
+	hex := m.Derive("0304")
+	step := hex.Derive("step2")
+	if hex.Seed() != "campaign/0304" || step.Seed() != "campaign/0304/step2" {
+		t.Errorf("derived seeds = %q, %q", hex.Seed(), step.Seed())
+	}
+	wantHex := []int{78, 68, 60, 15, 7, 76}
+	wantStep := []int{32, 78, 11, 47, 46, 85}
+	for i := range wantHex {
+		if got := hex.MustRoll("1d100"); got != wantHex[i] {
+			t.Errorf("hex roll %d = %d, want %d", i, got, wantHex[i])
+		}
+		if got := step.MustRoll("1d100"); got != wantStep[i] {
+			t.Errorf("step roll %d = %d, want %d", i, got, wantStep[i])
+		}
+	}
+}
+
+func TestDeriveIndependent(t *testing.T) {
+	m := newSeededManager(t, "subsector")
+	first := m.Derive("0305").MustRoll("10d100")
+
+	// Rolling on the parent or on a sibling must not change the stream of 0305.
+	m = newSeededManager(t, "subsector")
+	m.MustRoll("5d6")
+	sibling := m.Derive("0304")
+	for i := 0; i < 10; i++ {
+		sibling.MustRoll("3d6")
+	}
+	if again := m.Derive("0305").MustRoll("10d100"); again != first {
+		t.Errorf("Derive(0305) changed from %d to %d", first, again)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Snapshot and Replay Tests
+
+func TestSnapshotRestore(t *testing.T) {
+	managers := map[string]func() *dice.Manager{
+		"seeded":  func() *dice.Manager { return newSeededManager(t, "snapshot") },
+		"random":  func() *dice.Manager { return newSeededManager(t, "") },
+		"derived": func() *dice.Manager { return newSeededManager(t, "snapshot").Derive("0101") },
+		"pcg": func() *dice.Manager {
+			m, _ := dice.New("", dice.WithSource(dice.NewPCGSource(7, 9)))
+			return m
+		},
+		"chacha8": func() *dice.Manager {
+			m, _ := dice.New("", dice.WithSource(dice.NewChaCha8Source([32]byte{1, 2, 3})))
+			return m
+		},
+		"scripted": func() *dice.Manager { return newScriptedManager(t, 1, 2, 3, 4, 5, 6, 5, 4) },
+	}
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) {
+			m := newManager()
+			for i := 0; i < 7; i++ {
+				m.MustRoll("3d6")
+			}
+			snap, err := m.Snapshot()
+			if err != nil {
+				t.Fatalf("Snapshot failed: %v", err)
+			}
+			data, err := json.Marshal(snap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded := dice.Snapshot{}
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatal(err)
+			}
+			restored, err := dice.Restore(loaded)
+			if err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			if restored.Seed() != m.Seed() {
+				t.Errorf("restored seed = %q, want %q", restored.Seed(), m.Seed())
+			}
+			for i := 0; i < 20; i++ {
+				a, b := m.MustRoll("1d100"), restored.MustRoll("1d100")
+				if a != b {
+					t.Fatalf("roll %d after restore: original %d, restored %d", i, a, b)
+				}
+			}
+		})
+	}
+
+	m, _ := dice.New("", dice.WithSource(dice.NewCryptoSource()))
+	if _, err := m.Snapshot(); err == nil {
+		t.Errorf("Snapshot of crypto source should fail")
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	m := newSeededManager(t, "record")
+	m.StartRecording()
+	want := []int{m.MustRoll("3d6!"), m.MustRoll("4d6:dl1"), m.MustRoll("2D6")}
+	values := m.StopRecording()
+	if len(values) < 9 {
+		t.Fatalf("recorded %d values, want at least 9", len(values))
+	}
+
+	replay := dice.NewReplaySource(values...)
+	r, _ := dice.New("", dice.WithSource(replay))
+	got := []int{r.MustRoll("3d6!"), r.MustRoll("4d6:dl1"), r.MustRoll("2D6")}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("replayed roll %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("replay has %d unused values", replay.Remaining())
+	}
+	if _, err := r.Roll("1d6"); err == nil {
+		t.Errorf("rolling past the end of a replay should fail")
+	}
+}
+
 // ----------------------------------------------------------------------
 // D66, Flux, FluxGood, FluxBad Tests
 
@@ -229,6 +1362,80 @@ func TestD66(t *testing.T) {
 	// Digits still 0-9 due to clamping.
 }
 
+func TestDigitDice(t *testing.T) {
+	tests := []struct {
+		spec   string
+		script []int
+		mods   []int
+		want   string
+	}{
+		{"D66", []int{6, 1}, nil, "61"},
+		{"D66", []int{6, 1}, []int{5, -2}, "90"},
+		{"D666", []int{1, 2, 3}, nil, "123"},
+		{"D666", []int{1, 2, 3}, []int{5, 0, -3}, "620"},
+		{"D36", []int{3, 6}, []int{0, 1}, "37"},
+		{"D00", []int{10, 7}, nil, "07"},
+		{"d00", []int{10, 10}, []int{1}, "10"},
+		{"D0", []int{9}, []int{1}, "9"},
+	}
+	for _, tt := range tests {
+		got, err := newScriptedManager(t, tt.script...).Digits(tt.spec, tt.mods...)
+		if err != nil {
+			t.Errorf("Digits(%q, %v) error: %v", tt.spec, tt.mods, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Digits(%q, %v) with dice %v = %q, want %q", tt.spec, tt.mods, tt.script, got, tt.want)
+		}
+	}
+	if got := newScriptedManager(t, 4, 5, 6).D666(); got != "456" {
+		t.Errorf("D666() = %q, want 456", got)
+	}
+	for _, spec := range []string{"", "D", "66", "D1", "D6a", "D610"} {
+		if dice.IsDigitSpec(spec) {
+			t.Errorf("IsDigitSpec(%q) = true, want false", spec)
+		}
+		if _, err := dice.Digits(spec); err == nil {
+			t.Errorf("Digits(%q) should fail", spec)
+		}
+	}
+}
+
+func TestD100(t *testing.T) {
+	tests := []struct {
+		script []int
+		mods   []int
+		want   int
+	}{
+		{[]int{10, 10}, nil, 100},
+		{[]int{10, 5}, nil, 5},
+		{[]int{9, 9}, nil, 99},
+		{[]int{1, 10}, nil, 10},
+		{[]int{10, 3}, []int{1}, 13},      // tens 0 +1
+		{[]int{9, 9}, []int{1, 1}, 99},    // clamped digits
+		{[]int{1, 1}, []int{-1, -1}, 1},   // below the minimum
+		{[]int{10, 10}, []int{0, 0}, 100}, // zero DMs leave the roll unmodified
+		{[]int{10, 10}, []int{1}, 10},
+	}
+	for _, tt := range tests {
+		if got := newScriptedManager(t, tt.script...).D100(tt.mods...); got != tt.want {
+			t.Errorf("D100(%v) with dice %v = %d, want %d", tt.mods, tt.script, got, tt.want)
+		}
+	}
+	m := newSeededManager(t, "d100")
+	seen := map[int]bool{}
+	for range 2000 {
+		v := m.D100()
+		if v < 1 || v > 100 {
+			t.Fatalf("D100() = %d, want 1-100", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("D100 produced %d distinct values in 2000 rolls, want 100", len(seen))
+	}
+}
+
 func TestFluxFunctions(t *testing.T) {
 	m := newSeededManager(t, "fluxseed")
 
@@ -254,20 +1461,145 @@ func TestFluxFunctions(t *testing.T) {
 	}
 }
 
-func TestVariance(t *testing.T) {
-	m := newSeededManager(t, "varseed")
+func TestVariance(t *testing.T) {
+	m := newSeededManager(t, "varseed")
+
+	for i := 0; i < 100; i++ {
+		v := m.Variance()
+		if v < 0.0 || v > 1.0 {
+			t.Errorf("Variance() = %f, want in [0.0, 1.0]", v)
+		}
+		// Check it's a multiple of 0.001 (since 1d1001-1 gives 0-1000)
+		scaled := int(math.Round(v * 1000))
+		if float64(scaled)/1000 != v {
+			t.Errorf("Variance() = %f, not a multiple of 0.001", v)
+		}
+	}
+}
+
+func TestVarianceResolution(t *testing.T) {
+	m := newScriptedManager(t, 1, 3, 5)
+	for _, want := range []float64{0, 0.5, 1} {
+		if got := m.VarianceResolution(4); got != want {
+			t.Errorf("VarianceResolution(4) = %v, want %v", got, want)
+		}
+	}
+	// Variance keeps the stream of "1d1001-1" rolls.
+	a, b := newSeededManager(t, "variance"), newSeededManager(t, "variance")
+	for range 20 {
+		if got, want := a.Variance(), float64(b.MustRoll("1d1001-1"))/1000; got != want {
+			t.Fatalf("Variance() = %v, want %v", got, want)
+		}
+	}
+	if got := newScriptedManager(t, 1001).VarianceResolution(0); got != 1 {
+		t.Errorf("VarianceResolution(0) = %v, want the default resolution", got)
+	}
+}
+
+func TestContinuousDistributions(t *testing.T) {
+	const n = 20000
+	sample := func(draw func(m *dice.Manager) float64) []float64 {
+		m := newSeededManager(t, "continuous")
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = draw(m)
+		}
+		return out
+	}
+	meanSD := func(xs []float64) (float64, float64) {
+		var total, sq float64
+		for _, x := range xs {
+			total += x
+		}
+		mean := total / float64(len(xs))
+		for _, x := range xs {
+			sq += (x - mean) * (x - mean)
+		}
+		return mean, math.Sqrt(sq / float64(len(xs)))
+	}
+	tests := []struct {
+		name     string
+		draw     func(m *dice.Manager) float64
+		lo, hi   float64
+		mean, sd float64
+	}{
+		{"Uniform", func(m *dice.Manager) float64 { return m.Uniform(2, -2) }, -2, 2, 0, 4 / math.Sqrt(12)},
+		{"Normal", func(m *dice.Manager) float64 { return m.Normal(10, 2) }, math.Inf(-1), math.Inf(1), 10, 2},
+		{"Triangular", func(m *dice.Manager) float64 { return m.Triangular(0, 0.2, 1) }, 0, 1, 0.4, math.Sqrt(0.84 / 18)},
+		{"LogNormal", func(m *dice.Manager) float64 { return m.LogNormal(0, 0.5) }, 0, math.Inf(1),
+			math.Exp(0.125), math.Sqrt((math.Exp(0.25) - 1) * math.Exp(0.25))},
+	}
+	for _, tt := range tests {
+		xs := sample(tt.draw)
+		for _, x := range xs {
+			if x < tt.lo || x > tt.hi {
+				t.Fatalf("%s produced %v outside [%v, %v]", tt.name, x, tt.lo, tt.hi)
+			}
+		}
+		mean, sd := meanSD(xs)
+		if math.Abs(mean-tt.mean) > 0.03*math.Max(1, math.Abs(tt.mean)) || math.Abs(sd-tt.sd) > 0.03*math.Max(1, tt.sd) {
+			t.Errorf("%s: mean %.4f sd %.4f, want about %.4f and %.4f", tt.name, mean, sd, tt.mean, tt.sd)
+		}
+		if !slices.Equal(xs[:50], sample(tt.draw)[:50]) {
+			t.Errorf("%s is not reproducible from the seed", tt.name)
+		}
+	}
+	if got := newSeededManager(t, "flat").Triangular(3, 7, 3); got != 3 {
+		t.Errorf("Triangular over an empty range = %v, want 3", got)
+	}
+}
+
+func TestContinuousDrawsAreNotLogged(t *testing.T) {
+	m := newSeededManager(t, "quiet")
+	log := dice.NewMemoryLogger()
+	m.SetLogger(log)
+	m.StartRecording()
+	x := m.Normal(0, 1)
+	v := m.Variance()
+	m.MustRoll("2d6")
+	values := m.StopRecording()
+	if lines := log.Lines(); len(lines) != 1 || !strings.HasPrefix(lines[0], "2d6") {
+		t.Errorf("log = %q, want only the 2d6 roll", lines)
+	}
+	if len(values) != 5 {
+		t.Errorf("recorded %d values, want 2 for Normal, 1 for Variance and 2 for 2d6", len(values))
+	}
+	replay, err := dice.New("", dice.WithSource(dice.NewReplaySource(values...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rx, rv := replay.Normal(0, 1), replay.Variance(); rx != x || rv != v {
+		t.Errorf("replay = %v %v, want %v %v", rx, rv, x, v)
+	}
+}
 
-	for i := 0; i < 100; i++ {
-		v := m.Variance()
-		if v < 0.0 || v > 1.0 {
-			t.Errorf("Variance() = %f, want in [0.0, 1.0]", v)
+func TestFluxDice(t *testing.T) {
+	tests := []struct {
+		name   string
+		flux   func(m *dice.Manager) (int, error)
+		script []int
+		want   int
+	}{
+		{"d10 flux", func(m *dice.Manager) (int, error) { return m.FluxDice(2, 10) }, []int{9, 3}, 6},
+		{"2D6-2D6", func(m *dice.Manager) (int, error) { return m.FluxDice(4, 6, 1) }, []int{6, 5, 1, 2}, 9},
+		{"good", func(m *dice.Manager) (int, error) { return m.FluxGoodDice(4, 6) }, []int{1, 6, 2, 5}, 8},
+		{"bad", func(m *dice.Manager) (int, error) { return m.FluxBadDice(2, 10, -1) }, []int{2, 9}, -8},
+	}
+	for _, tt := range tests {
+		got, err := tt.flux(newScriptedManager(t, tt.script...))
+		if err != nil || got != tt.want {
+			t.Errorf("%s with dice %v = %d, %v; want %d", tt.name, tt.script, got, err, tt.want)
 		}
-		// Check it's a multiple of 0.001 (since 1d1001-1 gives 0-1000)
-		scaled := int(math.Round(v * 1000))
-		if float64(scaled)/1000 != v {
-			t.Errorf("Variance() = %f, not a multiple of 0.001", v)
+	}
+	for _, count := range []int{0, 1, 3, -2} {
+		if _, err := dice.FluxDice(count, 6); err == nil {
+			t.Errorf("FluxDice(%d, 6) should fail", count)
 		}
 	}
+	m := newScriptedManager(t, 2, 5)
+	if got := []int{m.Flux(), m.FluxGood(), m.FluxBad(1)}; !slices.Equal(got, []int{-3, 3, -2}) {
+		t.Errorf("Flux, FluxGood, FluxBad(1) with dice 2, 5 = %v, want [-3 3 -2]", got)
+	}
 }
 
 // ----------------------------------------------------------------------
@@ -315,26 +1647,395 @@ func TestConcurrentDefaultManager(t *testing.T) {
 	wg.Wait()
 }
 
+func TestConcurrentRollResult(t *testing.T) {
+	m := newSeededManager(t, "per-call")
+	log := dice.NewMemoryLogger()
+	m.SetLogger(log)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				val, res, err := m.RollResult("4d6:dl1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				raw := res.Raw()
+				if want := sumInts(raw) - slices.Min(raw); val != want || len(raw) != 4 {
+					t.Errorf("RollResult = %d with dice %v, want %d", val, raw, want)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(log.Records()); n != 16*50 {
+		t.Errorf("logged %d rolls, want %d", n, 16*50)
+	}
+}
+
+func TestShardsDeterministic(t *testing.T) {
+	const workers, rolls = 8, 200
+	run := func(parallel bool) [][]int {
+		root := newSeededManager(t, "shards")
+		out := make([][]int, workers)
+		var wg sync.WaitGroup
+		for w := range workers {
+			work := func() {
+				shard := root.Shard(w)
+				for range rolls {
+					out[w] = append(out[w], shard.MustRoll("3d6"))
+				}
+			}
+			if !parallel {
+				work()
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
+		return out
+	}
+
+	sequential, parallel := run(false), run(true)
+	for w := range workers {
+		if !slices.Equal(sequential[w], parallel[w]) {
+			t.Errorf("shard %d differs between sequential and parallel runs", w)
+		}
+	}
+	if slices.Equal(sequential[0], sequential[1]) {
+		t.Errorf("shards 0 and 1 produced the same stream")
+	}
+	if got, want := newSeededManager(t, "shards").Shard(3).Seed(), "shards/shard:3"; got != want {
+		t.Errorf("Shard(3).Seed() = %q, want %q", got, want)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Simulation Tests
+
+func TestRollN(t *testing.T) {
+	m := newScriptedManager(t, 1, 2, 3, 4, 5, 6)
+	got, err := m.RollN("2d6", 3, 1)
+	if err != nil {
+		t.Fatalf("RollN failed: %v", err)
+	}
+	if want := []int{4, 8, 12}; !slices.Equal(got, want) {
+		t.Errorf("RollN = %v, want %v", got, want)
+	}
+	if _, err := m.RollN("2d6", -1); err == nil {
+		t.Errorf("RollN with a negative count should fail")
+	}
+	if got, err := m.RollN("bad", 2); err == nil || len(got) != 0 {
+		t.Errorf("RollN(bad) = %v, %v; want an error", got, err)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	opts := dice.SimulateOptions{Seed: "simulate", Workers: 4}
+	st, err := dice.Simulate("2d6", 20000, opts)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if st.N != 20000 || st.Min != 2 || st.Max != 12 || st.BinWidth != 1 {
+		t.Errorf("Simulate = %v, width %d", st, st.BinWidth)
+	}
+	if math.Abs(st.Mean()-7) > 0.1 || math.Abs(st.StdDev()-math.Sqrt(35.0/6)) > 0.1 {
+		t.Errorf("mean %.3f sd %.3f, want about 7 and 2.415", st.Mean(), st.StdDev())
+	}
+	if math.Abs(st.Probability(7)-6.0/36) > 0.02 {
+		t.Errorf("P(7) = %.3f, want about 0.167", st.Probability(7))
+	}
+	if got := st.Percentile(0.5); got != 7 {
+		t.Errorf("median = %d, want 7", got)
+	}
+	if st.Percentile(0) != 2 || st.Percentile(1) != 12 {
+		t.Errorf("percentiles 0 and 1 = %d, %d; want 2, 12", st.Percentile(0), st.Percentile(1))
+	}
+
+	again, err := dice.Simulate("2d6", 20000, opts)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if again.String() != st.String() || !maps.Equal(again.Histogram, st.Histogram) {
+		t.Errorf("seeded Simulate is not reproducible: %v vs %v", again, st)
+	}
+
+	var summed int
+	for _, c := range st.Histogram {
+		summed += c
+	}
+	if summed != st.N {
+		t.Errorf("histogram holds %d outcomes, want %d", summed, st.N)
+	}
+}
+
+func TestSimulateMergeMatchesSingleWorker(t *testing.T) {
+	trial := func(m *dice.Manager) (int, error) { return m.Roll("3d6") }
+	parent := newSeededManager(t, "merge")
+	single, err := dice.SimulateFunc(900, dice.SimulateOptions{Manager: parent}, trial)
+	if err != nil {
+		t.Fatalf("SimulateFunc failed: %v", err)
+	}
+	// One worker rolls on Shard(0); re-rolling that stream by hand must give the same stats.
+	shard := parent.Shard(0)
+	var total, totalSq float64
+	for range 900 {
+		v := float64(shard.MustRoll("3d6"))
+		total += v
+		totalSq += v * v
+	}
+	mean := total / 900
+	if math.Abs(single.Mean()-mean) > 1e-9 || math.Abs(single.Variance()-(totalSq/900-mean*mean)) > 1e-6 {
+		t.Errorf("running stats %.6f/%.6f, want %.6f/%.6f", single.Mean(), single.Variance(), mean, totalSq/900-mean*mean)
+	}
+
+	multi, err := dice.SimulateFunc(900, dice.SimulateOptions{Manager: parent, Workers: 3}, trial)
+	if err != nil {
+		t.Fatalf("SimulateFunc failed: %v", err)
+	}
+	if multi.N != 900 || multi.Min < 3 || multi.Max > 18 {
+		t.Errorf("merged stats %v", multi)
+	}
+}
+
+func TestSimulateBoundedHistogram(t *testing.T) {
+	st, err := dice.Simulate("1d1000", 5000, dice.SimulateOptions{Seed: "bins", Workers: 2, MaxBins: 16})
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if len(st.Histogram) > 16 || st.BinWidth < 64 {
+		t.Errorf("histogram has %d bins of width %d, want at most 16 bins", len(st.Histogram), st.BinWidth)
+	}
+	if p := st.Percentile(0.5); p < 500-st.BinWidth-50 || p > 500+st.BinWidth+50 {
+		t.Errorf("median %d is off by more than a bin (width %d)", p, st.BinWidth)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	if _, err := dice.Simulate("bad", 10, dice.SimulateOptions{}); err == nil {
+		t.Errorf("Simulate(bad) should fail")
+	}
+	if _, err := dice.Simulate("2d6+@DM", 10, dice.SimulateOptions{}); err == nil {
+		t.Errorf("Simulate with an unresolved variable should fail")
+	}
+	st, err := dice.Simulate("2d6+@DM", 10, dice.SimulateOptions{Vars: dice.Vars{"DM": 10}, DMs: []int{1}})
+	if err != nil || st.Min < 13 {
+		t.Errorf("Simulate with vars = %v, %v", st, err)
+	}
+	boom := errors.New("boom")
+	_, err = dice.SimulateFunc(100, dice.SimulateOptions{Workers: 2}, func(*dice.Manager) (int, error) { return 0, boom })
+	if !errors.Is(err, boom) {
+		t.Errorf("SimulateFunc error = %v, want boom", err)
+	}
+}
+
 // ----------------------------------------------------------------------
 // Caching Tests
 
 func TestExpressionCache(t *testing.T) {
-	m := newSeededManager(t, "cacheseed")
+	cache := dice.NewCache(8)
+	m, err := dice.New("cacheseed", dice.WithCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := m.Roll("3d6+5"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The spelling and the canonical form share one entry when they are equal.
+	if _, err := m.Roll("3d6 + 5"); err != nil {
+		t.Fatal(err)
+	}
+	got := cache.Stats()
+	want := dice.CacheStats{Size: 2, Capacity: 8, Hits: 2, Misses: 2}
+	if got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+	if _, err := m.Derive("child").Roll("3d6+5"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Stats().Hits; got != 3 {
+		t.Errorf("derived Manager hits = %d, want 3 (shared cache)", got)
+	}
+	if _, err := dice.New("", dice.WithCache(nil)); err == nil {
+		t.Errorf("WithCache(nil) should fail")
+	}
+	if dice.DefaultCache().Stats().Capacity != dice.DefaultCacheSize {
+		t.Errorf("default cache capacity = %d, want %d", dice.DefaultCache().Stats().Capacity, dice.DefaultCacheSize)
+	}
+}
+
+func TestExpressionCacheEviction(t *testing.T) {
+	cache := dice.NewCache(3)
+	m, err := dice.New("lru", dice.WithCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expr := range []string{"1d6", "2d6", "3d6", "1d6", "4d6"} {
+		if _, err := m.Roll(expr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2d6 was the least recently used when 4d6 arrived.
+	got := cache.Stats()
+	if got.Size != 3 || got.Evictions != 1 || got.Misses != 4 || got.Hits != 1 {
+		t.Errorf("Stats = %+v, want size 3, 1 eviction, 4 misses, 1 hit", got)
+	}
+	if _, err := m.Roll("1d6"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Roll("2d6"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Stats(); got.Hits != 2 || got.Misses != 5 {
+		t.Errorf("after re-roll Stats = %+v, want 2 hits and 5 misses", got)
+	}
+
+	cache.Resize(1)
+	if got := cache.Stats(); got.Size != 1 || got.Capacity != 1 || got.Evictions != 4 {
+		t.Errorf("after Resize(1) Stats = %+v", got)
+	}
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Len after Purge = %d, want 0", cache.Len())
+	}
+}
 
-	expr := "3d6+5"
-	// First roll parses and caches.
-	_, err := m.Roll(expr)
+func TestExpressionCacheWarm(t *testing.T) {
+	cache := dice.NewCache(16)
+	err := cache.Warm("2d6", "1d100", "2d6:bogus", "3D6")
+	if err == nil || !strings.Contains(err.Error(), "2d6:bogus") {
+		t.Errorf("Warm error = %v, want one naming 2d6:bogus", err)
+	}
+	if got := cache.Stats(); got.Size != 3 || got.Hits != 0 || got.Misses != 0 {
+		t.Errorf("Stats after Warm = %+v, want 3 entries and no lookups counted", got)
+	}
+	m, err := dice.New("warm", dice.WithCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Roll("1d100"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Stats(); got.Hits != 1 || got.Misses != 0 {
+		t.Errorf("Stats after roll = %+v, want a hit on the warmed expression", got)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Introspection Tests
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		expr      string
+		canonical string
+		min, max  int
+	}{
+		{"2d6", "2d6", 2, 12},
+		{"4d6:dl1", "4d6:dl1", 3, 18},
+		{"1d6!", "1d6!", 1, 606},
+		{"2D6", "2D6", 11, 66},
+		{"2d6:r<3", "2d6:r<3", 6, 12},
+		{"3d6=3", "3d6=3", 0, 3},
+		{"4dF", "4dF", -4, 4},
+		{"1d6/(1d3-2)", "1d6/(1d3-2)", -6, 6},
+		{"2d6+1d3-2", "2d6+1d3-2", 1, 13},
+		{"max(1d6, 1d4)", "max(1d6,1d4)", 1, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			ex, err := dice.Inspect(tt.expr)
+			if err != nil {
+				t.Fatalf("Inspect(%q): %v", tt.expr, err)
+			}
+			if ex.Source != tt.expr {
+				t.Errorf("Source = %q, want %q", ex.Source, tt.expr)
+			}
+			if ex.Canonical != tt.canonical {
+				t.Errorf("Canonical = %q, want %q", ex.Canonical, tt.canonical)
+			}
+			if ex.Min != tt.min || ex.Max != tt.max {
+				t.Errorf("range = [%d, %d], want [%d, %d]", ex.Min, ex.Max, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestInspectGroups(t *testing.T) {
+	ex, err := dice.Inspect("4d6:dl1:r1+3DD6+3dF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.Groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(ex.Groups))
+	}
+	g := ex.Groups[0]
+	if g.Count != 4 || g.Faces != 6 || g.Type != dice.DiceSum || g.Die != "" || g.Explode != "" {
+		t.Errorf("group 0 = %+v", g)
+	}
+	var kinds []string
+	for _, m := range g.Modifiers {
+		kinds = append(kinds, m.Kind)
+	}
+	// Modifiers are listed in the order they apply: rerolls first, the sum last.
+	if want := []string{"reroll", "drop lowest", "sum"}; !slices.Equal(kinds, want) {
+		t.Fatalf("group 0 modifiers = %v, want %v", kinds, want)
+	}
+	if m := g.Modifiers[0]; m.Compare != "=" || m.Value != 1 || m.Text != "r1" {
+		t.Errorf("reroll = %+v, want =1", m)
+	}
+	if m := g.Modifiers[1]; m.Value != 1 || m.Text != "dl1" {
+		t.Errorf("drop lowest = %+v, want 1", m)
+	}
+	if ex.Groups[1].Type != dice.DiceDestructive || ex.Groups[1].Min != 2 || ex.Groups[1].Max != 12 {
+		t.Errorf("group 1 = %+v, want destructive 2-12", ex.Groups[1])
+	}
+	if ex.Groups[2].Die != "F" || ex.Groups[2].Faces != 3 {
+		t.Errorf("group 2 = %+v, want three-faced Fudge dice", ex.Groups[2])
+	}
+
+	ex, err = dice.Inspect("2D6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := ex.Groups[0]; g.Type != dice.DiceConcat || g.Min != 11 || g.Max != 66 {
+		t.Errorf("group = %+v, want concat 11-66", g)
+	}
+
+	ex, err = dice.Inspect("3d6!>5")
 	if err != nil {
 		t.Fatal(err)
 	}
+	if g := ex.Groups[0]; g.Explode != "!" || g.ExplodeAt != 5 {
+		t.Errorf("exploding group = %+v, want ! at 5", g)
+	}
+}
 
-	// We can't inspect cache directly, but we can verify that invalidating
-	// something doesn't happen. A second roll should use cache.
-	_, err = m.Roll(expr)
+func TestInspectWithVariables(t *testing.T) {
+	if _, err := dice.Inspect("2d6+@DM"); err == nil {
+		t.Errorf("Inspect should fail to range an unresolved variable")
+	}
+	ex, err := dice.InspectWith("2d6+@DM", dice.Vars{"DM": -2})
 	if err != nil {
 		t.Fatal(err)
 	}
-	// No direct assertion, but if cache were broken we'd see parsing errors.
+	if ex.Min != 0 || ex.Max != 10 {
+		t.Errorf("range = [%d, %d], want [0, 10]", ex.Min, ex.Max)
+	}
+	if !slices.Equal(ex.Variables, []string{"DM"}) {
+		t.Errorf("Variables = %v, want [DM]", ex.Variables)
+	}
+	if _, err := dice.Inspect("2d6:bogus"); err == nil {
+		t.Errorf("Inspect of an invalid expression should fail")
+	}
 }
 
 // ----------------------------------------------------------------------
@@ -373,6 +2074,185 @@ func TestResult(t *testing.T) {
 	}
 }
 
+// ----------------------------------------------------------------------
+// Context and Budget Tests
+
+func TestRollContext(t *testing.T) {
+	m := newSeededManager(t, "context")
+	v, err := m.RollContext(context.Background(), "2d6+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v < 3 || v > 13 {
+		t.Errorf("RollContext 2d6+1 = %d", v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.StartRecording()
+	if _, err := m.RollContext(ctx, "3d6"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled RollContext error = %v, want context.Canceled", err)
+	}
+	if drawn := m.StopRecording(); len(drawn) != 0 {
+		t.Errorf("cancelled roll drew %v", drawn)
+	}
+}
+
+func TestRollBudget(t *testing.T) {
+	m := newSeededManager(t, "budget")
+	if got := m.Budget(); got != dice.DefaultBudget() {
+		t.Errorf("Budget = %+v, want the default", got)
+	}
+	m.StartRecording()
+	if _, err := m.Roll("100000d1000"); !errors.Is(err, dice.ErrBudgetExceeded) {
+		t.Errorf("100000d1000 error = %v, want ErrBudgetExceeded", err)
+	}
+	if drawn := m.StopRecording(); len(drawn) != 0 {
+		t.Errorf("oversized pool drew %d dice before failing", len(drawn))
+	}
+
+	tight, err := dice.New("budget", dice.WithBudget(dice.Budget{MaxDice: 50, MaxRerolls: 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Five faces in six explode, so the chains run far past 50 dice.
+	if _, err := tight.Roll("30d6!>2"); !errors.Is(err, dice.ErrBudgetExceeded) {
+		t.Errorf("exploding chain error = %v, want ErrBudgetExceeded", err)
+	}
+	if _, err := tight.Roll("20d6:r<6"); !errors.Is(err, dice.ErrBudgetExceeded) {
+		t.Errorf("reroll error = %v, want ErrBudgetExceeded", err)
+	}
+	if _, err := tight.Roll("50d6"); err != nil {
+		t.Errorf("50d6 within budget failed: %v", err)
+	}
+	if got := tight.Derive("child").Budget(); got != tight.Budget() {
+		t.Errorf("derived Budget = %+v, want %+v", got, tight.Budget())
+	}
+
+	ctx := dice.ContextWithBudget(context.Background(), dice.Budget{MaxDice: 2})
+	if _, err := m.RollContext(ctx, "3d6"); !errors.Is(err, dice.ErrBudgetExceeded) {
+		t.Errorf("context budget error = %v, want ErrBudgetExceeded", err)
+	}
+	unlimited, err := dice.New("budget", dice.WithBudget(dice.Budget{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unlimited.Roll("20000d2"); err != nil {
+		t.Errorf("unlimited budget failed: %v", err)
+	}
+}
+
+func TestErrInvalidExpression(t *testing.T) {
+	_, err := dice.Roll("3x6")
+	if !errors.Is(err, dice.ErrInvalidExpression) {
+		t.Errorf("Roll error = %v, want ErrInvalidExpression", err)
+	}
+	if !strings.Contains(err.Error(), "3x6") {
+		t.Errorf("error %q should keep the parser message", err)
+	}
+	if err := dice.ValidateExpression("2d6:xyz"); !errors.Is(err, dice.ErrInvalidExpression) {
+		t.Errorf("ValidateExpression error = %v, want ErrInvalidExpression", err)
+	}
+	if _, err := dice.Roll("2d6:/0"); errors.Is(err, dice.ErrBudgetExceeded) {
+		t.Errorf("invalid expression reported as a spent budget: %v", err)
+	}
+}
+
+// ----------------------------------------------------------------------
+// Reverse Roll Tests
+
+func TestForce(t *testing.T) {
+	m := newSeededManager(t, "force")
+	tests := []struct {
+		expr  string
+		total int
+		mods  []int
+	}{
+		{"2d6", 12, nil},
+		{"10d6", 60, nil},
+		{"10d6", 11, nil},
+		{"4d6:dl1", 18, nil},
+		{"1d6!", 13, nil},
+		{"3d6=3", 3, nil},
+		{"20d6:r<3+5", 124, nil},
+		{"2d6", 9, []int{-3}},
+	}
+	for _, tt := range tests {
+		res, err := m.Force(tt.expr, tt.total, tt.mods...)
+		if err != nil {
+			t.Errorf("Force(%q, %d): %v", tt.expr, tt.total, err)
+			continue
+		}
+		// The dice must roll the total again when replayed.
+		replay, err := dice.New("", dice.WithSource(dice.NewReplaySource(res.Raw()...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(tt.expr, "!") && !strings.Contains(tt.expr, ":r") {
+			if got, err := replay.Roll(tt.expr, tt.mods...); err != nil || got != tt.total {
+				t.Errorf("Force(%q, %d) dice %v roll %d, %v", tt.expr, tt.total, res.Raw(), got, err)
+			}
+		}
+	}
+
+	for _, expr := range []string{"2d6*2", "3d6"} {
+		total := map[string]int{"2d6*2": 5, "3d6": 19}[expr]
+		if _, err := m.Force(expr, total); !errors.Is(err, dice.ErrUnreachable) {
+			t.Errorf("Force(%q, %d) error = %v, want ErrUnreachable", expr, total, err)
+		}
+	}
+	if _, err := m.Force("2d6:bogus", 7); !errors.Is(err, dice.ErrInvalidExpression) {
+		t.Errorf("Force of an invalid expression: %v", err)
+	}
+}
+
+func TestRollBetween(t *testing.T) {
+	m := newSeededManager(t, "between")
+	log := dice.NewMemoryLogger()
+	m.SetLogger(log)
+	m.StartRecording()
+	counts := map[int]int{}
+	for range 300 {
+		v, res, err := m.RollBetween("2d6", 10, 12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 10 || v > 12 || sumInts(res.Raw()) != v {
+			t.Fatalf("RollBetween = %d with dice %v", v, res.Raw())
+		}
+		counts[v]++
+	}
+	// Sampled with the true odds: 3, 2 and 1 ways in 36.
+	if !(counts[10] > counts[11] && counts[11] > counts[12]) {
+		t.Errorf("totals %v do not follow the 2d6 odds", counts)
+	}
+	if got := len(log.Records()); got != 300 {
+		t.Errorf("logged %d rolls, want 300", got)
+	}
+
+	// A recording of forced rolls replays them exactly, and so do the rolls after them.
+	m.StopRecording()
+	m.StartRecording()
+	want, _, err := m.RollBetween("3d6", 17, 18, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := m.MustRoll("2d6")
+	if rr := log.Records()[len(log.Records())-2]; rr.Seed != m.Seed() {
+		t.Errorf("forced roll logged with seed %q, want %q", rr.Seed, m.Seed())
+	}
+	replay, err := dice.New("", dice.WithSource(dice.NewReplaySource(m.StopRecording()...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := replay.RollBetween("3d6", 17, 18, 2); err != nil || got != want {
+		t.Errorf("replayed forced roll = %d, %v, want %d", got, err, want)
+	}
+	if got, err := replay.Roll("2d6"); err != nil || got != after {
+		t.Errorf("replayed roll after the forced one = %d, %v, want %d", got, err, after)
+	}
+}
+
 // ----------------------------------------------------------------------
 // Edge Cases and Boundaries
 
@@ -424,6 +2304,30 @@ func BenchmarkRoll(b *testing.B) {
 	}
 }
 
+// BenchmarkRollParallelShared rolls on one Manager from every core. Only the draws
+// from the shared stream are serialised.
+func BenchmarkRollParallelShared(b *testing.B) {
+	m, _ := dice.New("benchmark")
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = m.Roll("4d6:dl1+2")
+		}
+	})
+}
+
+// BenchmarkRollParallelSharded gives every goroutine its own shard, which should scale
+// with the number of cores: go test -bench Parallel -cpu 1,2,4,8.
+func BenchmarkRollParallelSharded(b *testing.B) {
+	m, _ := dice.New("benchmark")
+	var next atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		shard := m.Shard(int(next.Add(1)))
+		for pb.Next() {
+			_, _ = shard.Roll("4d6:dl1+2")
+		}
+	})
+}
+
 func BenchmarkMustRoll(b *testing.B) {
 	m, _ := dice.New("benchmark")
 	b.ResetTimer()
@@ -0,0 +1,65 @@
+package dice
+
+import "fmt"
+
+// Resolver supplies the values of the named variables of an expression ("@SizeDM").
+// Resolve reports false for an unknown name, which fails the roll.
+type Resolver interface {
+	Resolve(name string) (int, bool)
+}
+
+// Vars is a Resolver backed by a map. Names are given without the "@" prefix.
+type Vars map[string]int
+
+// Resolve returns the value stored under name.
+func (v Vars) Resolve(name string) (int, bool) {
+	val, ok := v[name]
+	return val, ok
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(name string) (int, bool)
+
+// Resolve calls f(name).
+func (f ResolverFunc) Resolve(name string) (int, bool) {
+	return f(name)
+}
+
+// RollWith is like Roll but resolves the variables of the expression from vars:
+// RollWith("2d6+@SizeDM-@AtmoDM", Vars{"SizeDM": 1, "AtmoDM": 2}).
+func (m *Manager) RollWith(expr string, vars Resolver, mods ...int) (int, error) {
+	intrpr, _, err := m.evaluate(expr, vars, mods)
+	if err != nil {
+		return 0, fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
+	}
+	return intrpr.sum, nil
+}
+
+// RollWith uses the default manager to evaluate an expression with variables.
+func RollWith(expr string, vars Resolver, mods ...int) (int, error) {
+	return defaultManager.RollWith(expr, vars, mods...)
+}
+
+// RollCodeWith is like RollCode but resolves the variables of the expression from vars.
+func (m *Manager) RollCodeWith(expr string, vars Resolver, mods ...int) (string, error) {
+	intrpr, _, err := m.evaluate(expr, vars, mods)
+	if err != nil {
+		return "", fmt.Errorf("roll %q %v failed: %w", expr, mods, err)
+	}
+	return intrpr.code, nil
+}
+
+// RollCodeWith uses the default manager to evaluate an expression with variables as a string code.
+func RollCodeWith(expr string, vars Resolver, mods ...int) (string, error) {
+	return defaultManager.RollCodeWith(expr, vars, mods...)
+}
+
+// Variables returns the names of the variables used by the expression, in order of
+// first appearance and without the "@" prefix.
+func Variables(expr string) ([]string, error) {
+	exp, err := lookupExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return exp.variables(), nil
+}
@@ -0,0 +1,23 @@
+package systemgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerationLog(t *testing.T) {
+	g := New(SubAverage, "log")
+	res := &GenerationResult{}
+	stop := g.record(res)
+	g.dice.MustRoll("2d6")
+	g.dice.MustRoll("1d6", 1)
+	stop()
+	g.dice.MustRoll("2d6")
+
+	if len(res.Log) != 2 {
+		t.Fatalf("Log = %q, want the two rolls made while recording", res.Log)
+	}
+	if !strings.HasPrefix(res.Log[0], "2d6 [") || !strings.HasPrefix(res.Log[1], "1d6 DM[1] [") {
+		t.Errorf("Log = %q, want the transcript lines of 2d6 and 1d6 DM[1]", res.Log)
+	}
+}
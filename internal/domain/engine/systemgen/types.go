@@ -301,6 +301,7 @@ type SystemQuirk struct {
 type Generator struct {
 	subsectorType SubsectorType
 	seed          string
+	dice          *dice.Manager
 }
 
 // GenerationResult is the output of a single hex generation run.
@@ -312,19 +313,40 @@ type GenerationResult struct {
 
 // New creates a Generator configured with the given subsector density and RNG seed.
 func New(subsectorType SubsectorType, seed string) *Generator {
+	m, _ := dice.New(seed) // without options New cannot fail
 	return &Generator{
 		subsectorType: subsectorType,
 		seed:          seed,
+		dice:          m,
 	}
 }
 
+// resultLog is a dice.Logger writing the roll transcript to the Log of a
+// GenerationResult. A Generator generates one hex at a time, so it takes no lock.
+type resultLog struct {
+	res *GenerationResult
+}
+
+func (l resultLog) LogRoll(rr dice.RollRecord) {
+	l.res.Log = append(l.res.Log, rr.String())
+}
+
+// record feeds the rolls of the Generator into the Log of res until the returned
+// function is called.
+func (g *Generator) record(res *GenerationResult) (stop func()) {
+	g.dice.SetLogger(resultLog{res: res})
+	return func() { g.dice.SetLogger(nil) }
+}
+
 // GenerateHex generates a complete star system for the hex at (row, col).
 //
 // The generation follows the 18-step process defined in System_Generation_Extended.md.
 // Returns ErrNoObjectInHex if step 1 determines no object exists, or
 // ErrBlackHole if the object is a black hole (no further generation).
 func (g *Generator) GenerateHex(row, col int) (*GenerationResult, error) {
-	// TODO: implement full generation
+	res := &GenerationResult{}
+	defer g.record(res)()
+	// TODO: implement full generation rolling on g.dice, then return res
 	return nil, nil
 }
 
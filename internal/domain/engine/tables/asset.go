@@ -0,0 +1,187 @@
+package tables
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Asset types, the "type" of the documents in assets/. A document without a type is
+// a plain GameTable.
+const (
+	AssetTable             = "table"
+	AssetFormula           = "formula"
+	AssetFormulaCollection = "formula_collection"
+	AssetFormulaTable      = "formula_table"
+	AssetGovernmentTable   = "government_table"
+	AssetModifierTable     = "modifier_table"
+	AssetRingDetails       = "ring_details_extended"
+	AssetStarZoneTable     = "star_zone_table"
+	AssetThresholdTable    = "threshold_table"
+)
+
+// Asset is the model of a document of the assets directory.
+type Asset interface {
+	// AssetName returns the "name" of the document.
+	AssetName() string
+	// Validate checks the model after decoding.
+	Validate() error
+}
+
+// AssetDecoder reads a document of one type into its model. The "type" field is
+// removed from the document first, so decoders can reject unknown fields.
+type AssetDecoder func(data []byte) (Asset, error)
+
+var assetTypes = &assetRegistry{
+	decoders: map[string]AssetDecoder{
+		AssetTable:             decodeGameTable,
+		AssetFormula:           decodeFormula,
+		AssetFormulaCollection: decodeFormulaCollection,
+		AssetFormulaTable:      decodeFormulaTable,
+		AssetGovernmentTable:   decodeGovernmentTable,
+		AssetModifierTable:     decodeModifierTable,
+		AssetRingDetails:       decodeRingDetails,
+		AssetStarZoneTable:     decodeStarZoneTable,
+		AssetThresholdTable:    decodeThresholdTable,
+	},
+}
+
+type assetRegistry struct {
+	mu       sync.RWMutex
+	decoders map[string]AssetDecoder
+}
+
+func (ar *assetRegistry) get(typ string) (AssetDecoder, bool) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	dec, ok := ar.decoders[typ]
+	return dec, ok
+}
+
+// RegisterAssetType adds a document type to the loader. A type can be registered
+// only once; the built-in types are already registered.
+func RegisterAssetType(typ string, decode AssetDecoder) error {
+	if typ == "" {
+		return fmt.Errorf("empty asset type")
+	}
+	if decode == nil {
+		return fmt.Errorf("asset type %q has no decoder", typ)
+	}
+	assetTypes.mu.Lock()
+	defer assetTypes.mu.Unlock()
+	if _, ok := assetTypes.decoders[typ]; ok {
+		return fmt.Errorf("asset type %q is already registered", typ)
+	}
+	assetTypes.decoders[typ] = decode
+	return nil
+}
+
+// AssetTypes returns the registered document types, sorted.
+func AssetTypes() []string {
+	assetTypes.mu.RLock()
+	defer assetTypes.mu.RUnlock()
+	return slices.Sorted(maps.Keys(assetTypes.decoders))
+}
+
+// DecodeAsset reads a document with the decoder of its "type" and validates the
+// model. It returns the type, AssetTable for a document without one.
+func DecodeAsset(data []byte) (string, Asset, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal asset: %w", err)
+	}
+	return decodeFields(fields)
+}
+
+// decodeFields decodes a document already split into its fields.
+func decodeFields(fields map[string]json.RawMessage) (string, Asset, error) {
+	typ, err := assetType(fields)
+	if err != nil {
+		return "", nil, err
+	}
+	decode, ok := assetTypes.get(typ)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown asset type %q (known types: %s)", typ, strings.Join(AssetTypes(), ", "))
+	}
+	body, err := json.Marshal(withoutField(fields, "type"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+	asset, err := decode(body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode %s: %w", typ, err)
+	}
+	if err := asset.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid %s: %w", typ, err)
+	}
+	return typ, asset, nil
+}
+
+// assetType returns the "type" of a document, AssetTable when it has none.
+func assetType(fields map[string]json.RawMessage) (string, error) {
+	typ := AssetTable
+	if raw, ok := fields["type"]; ok {
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return "", fmt.Errorf("failed to read asset type: %w", err)
+		}
+	}
+	return typ, nil
+}
+
+// withoutField returns a copy of the fields of a document without one of them.
+func withoutField(fields map[string]json.RawMessage, name string) map[string]json.RawMessage {
+	out := maps.Clone(fields)
+	delete(out, name)
+	return out
+}
+
+// LoadAsset reads and decodes the document at path.
+func LoadAsset(path string) (string, Asset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read asset file: %w", err)
+	}
+	typ, asset, err := DecodeAsset(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("asset %q: %w", path, err)
+	}
+	return typ, asset, nil
+}
+
+// decodeStrict unmarshals data into v, failing on fields v does not know.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// AssetName returns the name of the table.
+func (t GameTable) AssetName() string {
+	return t.Name
+}
+
+func decodeGameTable(data []byte) (Asset, error) {
+	t := GameTable{}
+	if err := t.decode(data, decodeStrict); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// decodeRingDetails reads a plain table written with "base_roll" for its expression.
+func decodeRingDetails(data []byte) (Asset, error) {
+	doc := struct {
+		Name     string            `json:"name"`
+		BaseRoll string            `json:"base_roll"`
+		Data     map[string]string `json:"data"`
+	}{}
+	if err := decodeStrict(data, &doc); err != nil {
+		return nil, err
+	}
+	return New(doc.Name, doc.BaseRoll, doc.Data), nil
+}
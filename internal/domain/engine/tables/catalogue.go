@@ -0,0 +1,254 @@
+package tables
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// assetExt is the extension of the documents a Catalogue loads; other files of a
+// layer, such as archives, are skipped.
+const assetExt = ".json"
+
+// Catalogue holds the assets of one or more layers, keyed by ID: the path of the
+// document that first defined the asset, without its extension, e.g.
+// "generic_gov_terran_hz".
+type Catalogue struct {
+	assets map[string]catalogued
+	names  map[string]string
+}
+
+type catalogued struct {
+	info  AssetInfo
+	asset Asset
+	doc   map[string]json.RawMessage
+}
+
+// AssetInfo describes an asset of a Catalogue and where it comes from: Layer and
+// Path of the document that defines it, the documents of lower layers it Replaced
+// and the Patches applied to it, in order.
+type AssetInfo struct {
+	ID       string
+	Name     string
+	Type     string
+	Layer    string
+	Path     string
+	Replaced []AssetSource
+	Patches  []AssetSource
+}
+
+// Origin describes the provenance of the asset in one line, e.g.
+// "campaign:quirks.json (replaces builtin:system_quirks.json)".
+func (info AssetInfo) Origin() string {
+	s := info.source().String()
+	var notes []string
+	if len(info.Replaced) > 0 {
+		notes = append(notes, "replaces "+joinSources(info.Replaced))
+	}
+	if len(info.Patches) > 0 {
+		notes = append(notes, "patched by "+joinSources(info.Patches))
+	}
+	if len(notes) > 0 {
+		s += " (" + strings.Join(notes, "; ") + ")"
+	}
+	return s
+}
+
+func (info AssetInfo) source() AssetSource {
+	return AssetSource{Layer: info.Layer, Path: info.Path}
+}
+
+func joinSources(sources []AssetSource) string {
+	s := make([]string, len(sources))
+	for i, src := range sources {
+		s[i] = src.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// LoadCatalogueDir loads the catalogue of a directory on disk, as a single layer.
+func LoadCatalogueDir(dir string) (*Catalogue, error) {
+	return LoadLayers(DirLayer(dir))
+}
+
+// Len returns the number of assets.
+func (c *Catalogue) Len() int {
+	return len(c.assets)
+}
+
+// Get returns the asset with the given ID.
+func (c *Catalogue) Get(id string) (Asset, bool) {
+	entry, ok := c.assets[id]
+	return entry.asset, ok
+}
+
+// Named returns the description of the asset with the given name.
+func (c *Catalogue) Named(name string) (AssetInfo, bool) {
+	id, ok := c.names[name]
+	if !ok {
+		return AssetInfo{}, false
+	}
+	return c.assets[id].info, true
+}
+
+// Info returns the description of the asset with the given ID.
+func (c *Catalogue) Info(id string) (AssetInfo, bool) {
+	entry, ok := c.assets[id]
+	return entry.info, ok
+}
+
+// List returns the assets of the given types, or all of them, sorted by ID.
+func (c *Catalogue) List(types ...string) []AssetInfo {
+	var infos []AssetInfo
+	for _, id := range slices.Sorted(maps.Keys(c.assets)) {
+		info := c.assets[id].info
+		if len(types) == 0 || slices.Contains(types, info.Type) {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Table returns a plain table: a document without a type or a ring details table.
+func (c *Catalogue) Table(id string) (GameTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return GameTable{}, err
+	}
+	t, ok := a.(GameTable)
+	if !ok {
+		return GameTable{}, c.mismatch(id, "table")
+	}
+	return t, nil
+}
+
+// Formula returns a "formula" asset.
+func (c *Catalogue) Formula(id string) (Formula, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return Formula{}, err
+	}
+	f, ok := a.(Formula)
+	if !ok {
+		return Formula{}, c.mismatch(id, AssetFormula)
+	}
+	return f, nil
+}
+
+// FormulaCollection returns a "formula_collection" asset.
+func (c *Catalogue) FormulaCollection(id string) (FormulaCollection, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return FormulaCollection{}, err
+	}
+	fc, ok := a.(FormulaCollection)
+	if !ok {
+		return FormulaCollection{}, c.mismatch(id, AssetFormulaCollection)
+	}
+	return fc, nil
+}
+
+// FormulaTable returns a "formula_table" asset.
+func (c *Catalogue) FormulaTable(id string) (FormulaTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return FormulaTable{}, err
+	}
+	ft, ok := a.(FormulaTable)
+	if !ok {
+		return FormulaTable{}, c.mismatch(id, AssetFormulaTable)
+	}
+	return ft, nil
+}
+
+// GovernmentTable returns a "government_table" asset.
+func (c *Catalogue) GovernmentTable(id string) (GovernmentTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return GovernmentTable{}, err
+	}
+	gt, ok := a.(GovernmentTable)
+	if !ok {
+		return GovernmentTable{}, c.mismatch(id, AssetGovernmentTable)
+	}
+	return gt, nil
+}
+
+// ModifierTable returns a "modifier_table" asset.
+func (c *Catalogue) ModifierTable(id string) (ModifierTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return ModifierTable{}, err
+	}
+	mt, ok := a.(ModifierTable)
+	if !ok {
+		return ModifierTable{}, c.mismatch(id, AssetModifierTable)
+	}
+	return mt, nil
+}
+
+// StarZoneTable returns a "star_zone_table" asset.
+func (c *Catalogue) StarZoneTable(id string) (StarZoneTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return StarZoneTable{}, err
+	}
+	szt, ok := a.(StarZoneTable)
+	if !ok {
+		return StarZoneTable{}, c.mismatch(id, AssetStarZoneTable)
+	}
+	return szt, nil
+}
+
+// ThresholdTable returns a "threshold_table" asset.
+func (c *Catalogue) ThresholdTable(id string) (ThresholdTable, error) {
+	a, err := c.find(id)
+	if err != nil {
+		return ThresholdTable{}, err
+	}
+	tt, ok := a.(ThresholdTable)
+	if !ok {
+		return ThresholdTable{}, c.mismatch(id, AssetThresholdTable)
+	}
+	return tt, nil
+}
+
+// StarZone returns the row of a star class from the star zone table of any
+// luminosity class, e.g. "G2 V" or "L5".
+func (c *Catalogue) StarZone(class string) (StarZone, bool) {
+	for _, info := range c.List(AssetStarZoneTable) {
+		if star, ok := c.assets[info.ID].asset.(StarZoneTable).Star(class); ok {
+			return star, true
+		}
+	}
+	return StarZone{}, false
+}
+
+// Collection returns the plain tables and the government tables of the catalogue
+// as a Collection, where they are rolled by name.
+func (c *Catalogue) Collection(name string) (*Collection, error) {
+	var tables []GameTable
+	for _, info := range c.List() {
+		switch a := c.assets[info.ID].asset.(type) {
+		case GameTable:
+			tables = append(tables, a)
+		case GovernmentTable:
+			tables = append(tables, a.Table())
+		}
+	}
+	return NewCollection(name, tables...)
+}
+
+func (c *Catalogue) find(id string) (Asset, error) {
+	entry, ok := c.assets[id]
+	if !ok {
+		return nil, fmt.Errorf("asset %q not found", id)
+	}
+	return entry.asset, nil
+}
+
+func (c *Catalogue) mismatch(id, want string) error {
+	return fmt.Errorf("asset %q is a %s, not a %s", id, c.assets[id].info.Type, want)
+}
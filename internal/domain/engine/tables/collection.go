@@ -2,9 +2,12 @@ package tables
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 type Collection struct {
@@ -12,6 +15,7 @@ type Collection struct {
 	Tables       map[string]GameTable
 	rollSequence []string
 	results      []string
+	modifiers    map[string][]ModifierTable
 }
 
 func NewCollection(name string, tables ...GameTable) (*Collection, error) {
@@ -40,62 +44,177 @@ func (tc *Collection) Reset() {
 	tc.results = []string{}
 }
 
-func (tc *Collection) Roll(roller TableRoller, name string, mods ...int) (string, error) {
+// Roll rolls on the named table and returns the row it lands on: the text of a
+// string entry, and the fields of a structured one. Inline rolls ("{2d6-1}") and
+// references to other tables ("{@Government}") in the row are resolved through the
+// roller, recursively; the row's Trace lists them. mods apply to the roll on the
+// named table only.
+func (tc *Collection) Roll(roller TableRoller, name string, mods ...int) (Row, error) {
+	return tc.roll(roller, name, mods, nil, nil)
+}
+
+// RollWith rolls like Roll in the context of attrs, e.g. the codes of the world being
+// generated: the DMs of the modifier tables added to the table whose conditions hold
+// for attrs are summed and added to mods, and the row's Modifiers explain them.
+// References in the row roll with the same attributes.
+func (tc *Collection) RollWith(roller TableRoller, name string, attrs Attributes, mods ...int) (Row, error) {
+	if attrs == nil {
+		attrs = Attributes{}
+	}
+	return tc.roll(roller, name, mods, attrs, nil)
+}
+
+// AddModifiers adds modifier tables to the named table for RollWith. Only tables
+// rolled with a dice expression take DMs; D66 and digit tables return an error.
+func (tc *Collection) AddModifiers(name string, mts ...ModifierTable) error {
+	table, ok := tc.Tables[name]
+	if !ok {
+		return fmt.Errorf("table %q not found in collection %q", name, tc.Name)
+	}
+	if table.D66 || isDigitExpression(table.Expression) {
+		return fmt.Errorf("add modifiers to table %q: digit table %q cannot take DMs", table.Name, table.Expression)
+	}
+	for _, mt := range mts {
+		if err := mt.Validate(); err != nil {
+			return fmt.Errorf("add modifiers to table %q: %w", table.Name, err)
+		}
+	}
+	if tc.modifiers == nil {
+		tc.modifiers = map[string][]ModifierTable{}
+	}
+	tc.modifiers[name] = append(tc.modifiers[name], mts...)
+	return nil
+}
+
+// explain applies the modifier tables of the named table to attrs.
+func (tc *Collection) explain(name string, attrs Attributes) (Explanation, error) {
+	e := Explanation{}
+	for _, mt := range tc.modifiers[name] {
+		applied, err := mt.Apply(attrs)
+		if err != nil {
+			return Explanation{}, fmt.Errorf("roll on table %q: %w", name, err)
+		}
+		e.Applied = append(e.Applied, applied.Applied...)
+		e.Total += applied.Total
+	}
+	return e, nil
+}
+
+// roll rolls on a table below the tables of chain, which are being resolved. DMs
+// apply when attrs is not nil.
+func (tc *Collection) roll(roller TableRoller, name string, mods []int, attrs Attributes, chain []string) (Row, error) {
 	table := GameTable{}
 	var err error
 	if roller == nil {
-		return "", fmt.Errorf("nil roller provided")
+		return Row{}, fmt.Errorf("nil roller provided")
 	}
 	if found, ok := tc.Tables[name]; !ok {
-		return "", fmt.Errorf("table %q not found in collection %q", name, tc.Name)
+		return Row{}, fmt.Errorf("table %q not found in collection %q", name, tc.Name)
 	} else {
 		table = found
 	}
+	var dms Explanation
+	if attrs != nil && len(tc.modifiers[name]) > 0 {
+		if table.D66 || isDigitExpression(table.Expression) {
+			return Row{}, fmt.Errorf("roll on table %q: digit table %q cannot take DMs", table.Name, table.Expression)
+		}
+		if dms, err = tc.explain(name, attrs); err != nil {
+			return Row{}, err
+		}
+		mods = append(slices.Clone(mods), dms.Total)
+	}
 	index := -1002 //imposible index
 	indexStr := "<not set>"
-	result := ""
-	switch table.D66 {
-	case true:
+	key := ""
+	switch {
+	case table.D66:
 		indexStr = roller.D66(mods...)
-		result = table.Data[indexStr]
-	case false:
+		key = table.keyFor(indexStr)
+	case isDigitExpression(table.Expression):
+		dr, ok := roller.(DigitRoller)
+		if !ok {
+			return Row{}, fmt.Errorf("roll on table %q: roller cannot roll digit dice %q", table.Name, table.Expression)
+		}
+		indexStr, err = dr.Digits(table.Expression, mods...)
+		if err != nil {
+			return Row{}, fmt.Errorf("roll on table %q (expression %q %v) failed: %w", table.Name, table.Expression, mods, err)
+		}
+		key = table.keyFor(indexStr)
+	default:
 		index, err = roller.Roll(table.Expression, mods...)
 		if err != nil {
-			return "", fmt.Errorf("roll on table %q (expression %q %v) failed: %w", table.Name, table.Expression, mods, err)
-		}
-	search_loop:
-		for indexStr, value := range table.Data {
-			candidates, _ := stringToIndexes(indexStr) //skip error because it supposed to be validated by now
-			for _, match := range candidates {
-				if index != match {
-					continue
-				}
-				result = value
-				break search_loop
-			}
+			return Row{}, fmt.Errorf("roll on table %q (expression %q %v) failed: %w", table.Name, table.Expression, mods, err)
 		}
+		key = table.keyAt(index)
 	}
-	if result == "" {
-		return result, fmt.Errorf("result is empty in table %q (index=%d (or %q))", table.Name, index, indexStr)
+	result := table.row(key)
+	if key == "" || result.Text == "" {
+		return Row{}, fmt.Errorf("result is empty in table %q (index=%d (or %q))", table.Name, index, indexStr)
 	}
-	tc.results = append(tc.results, result)
+	tc.results = append(tc.results, result.Text)
 	tc.rollSequence = append(tc.rollSequence, table.Name)
-	return result, nil
+	result.Modifiers = dms
+	return tc.resolve(roller, table, result, attrs, append(slices.Clone(chain), table.Name))
+}
+
+// Force rolls on the table so that it lands on result, e.g. a result picked by the
+// game master, while the roller still produces dice for it. The keys holding result
+// are tried in ascending order and the first whose range the expression can reach
+// wins. Only tables rolled with a dice expression can be forced; D66 and digit
+// tables return an error. result is the text of the row, its label if it is
+// structured, before its placeholders are resolved; they are resolved when the
+// roller is also a TableRoller.
+func (tc *Collection) Force(roller RangeRoller, name, result string, mods ...int) (Row, error) {
+	if roller == nil {
+		return Row{}, fmt.Errorf("nil roller provided")
+	}
+	table, ok := tc.Tables[name]
+	if !ok {
+		return Row{}, fmt.Errorf("table %q not found in collection %q", name, tc.Name)
+	}
+	if table.D66 || isDigitExpression(table.Expression) {
+		return Row{}, fmt.Errorf("force on table %q: digit table %q cannot be forced", table.Name, table.Expression)
+	}
+	ranges := table.rangesOf(result)
+	if len(ranges) == 0 {
+		return Row{}, fmt.Errorf("force on table %q: no entry %q", table.Name, result)
+	}
+	var errs []error
+	for _, r := range ranges {
+		index, _, err := roller.RollBetween(table.Expression, r[0], r[1], mods...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		row := table.row(table.keyAt(index))
+		if row.Text != result {
+			return Row{}, fmt.Errorf("force on table %q: roller returned %d outside [%d, %d]", table.Name, index, r[0], r[1])
+		}
+		tc.results = append(tc.results, result)
+		tc.rollSequence = append(tc.rollSequence, table.Name)
+		if tr, ok := roller.(TableRoller); ok {
+			return tc.resolve(tr, table, row, nil, []string{table.Name})
+		}
+		return row, nil
+	}
+	return Row{}, fmt.Errorf("force on table %q (expression %q %v) to %q failed: %w", table.Name, table.Expression, mods, result, errors.Join(errs...))
 }
 
-func (tc *Collection) RollCascade(roller TableRoller, name string) (string, error) {
+// RollCascade rolls on the named table and goes on with the table named by the text
+// of each row, until a row names no table. It returns the last row.
+func (tc *Collection) RollCascade(roller TableRoller, name string) (Row, error) {
 	maxDepth := 1000
 	currentTable := name
 	if name == "" {
-		return "", fmt.Errorf("no name for starting table")
+		return Row{}, fmt.Errorf("no name for starting table")
 	}
 	for depth := range maxDepth {
 		result, err := tc.Roll(roller, currentTable)
 		if err != nil {
-			return "", fmt.Errorf("cascade failed at depth %d: %w", depth, err)
+			return Row{}, fmt.Errorf("cascade failed at depth %d: %w", depth, err)
 		}
 
-		nextTable, ok := tc.Tables[result]
+		nextTable, ok := tc.Tables[result.Text]
 		if !ok {
 			return result, nil
 		}
@@ -103,7 +222,20 @@ func (tc *Collection) RollCascade(roller TableRoller, name string) (string, erro
 		currentTable = nextTable.Name
 	}
 
-	return "", fmt.Errorf("cascade exceeded max depth %d", maxDepth)
+	return Row{}, fmt.Errorf("cascade exceeded max depth %d", maxDepth)
+}
+
+// Expressions returns the dice expressions of the tables of the collection, sorted and
+// without duplicates, e.g. to warm a dice.Cache before rolling. D66 and digit tables
+// roll through TableRoller.D66 and DigitRoller and are left out.
+func (tc *Collection) Expressions() []string {
+	seen := make(map[string]bool, len(tc.Tables))
+	for _, t := range tc.Tables {
+		if !t.D66 && !isDigitExpression(t.Expression) {
+			seen[t.Expression] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
 }
 
 func (tc *Collection) Validate() error {
@@ -117,6 +249,11 @@ func (tc *Collection) Validate() error {
 		if err := t.Validate(); err != nil {
 			return fmt.Errorf("failed to validate collection %q: %w", tc.Name, err)
 		}
+		for _, ref := range t.references() {
+			if _, ok := tc.Tables[ref]; !ok {
+				return fmt.Errorf("failed to validate collection %q: table %q refers to unknown table %q", tc.Name, t.Name, ref)
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,438 @@
+package tables
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Attributes are the named values conditions are evaluated against, e.g. the codes
+// of a world being generated. A value is a number (any int or float type), a string
+// or a bool.
+type Attributes map[string]any
+
+// Condition is a parsed condition of the modifier language:
+//
+//	Atmosphere in 4..5
+//	Hydrographics in [3..4, 10]
+//	Size >= 6 and Zone == "habitable"
+//	Star in ["O", "B", "A", "M"]
+//	not Oxygen or Temperature < 0
+//
+// Attribute names are identifiers. A value is a number, a string in single or double
+// quotes, true or false. Comparisons are ==, !=, <, <=, > and >= (the ordering ones need numbers);
+// "in" takes a range lo..hi or a bracketed list of values and ranges; a bare name
+// needs a bool. "not" binds tighter than "and", "and" tighter than "or", and
+// parentheses group.
+type Condition struct {
+	text  string
+	root  condNode
+	names []string
+}
+
+// ParseCondition parses a condition.
+func ParseCondition(text string) (Condition, error) {
+	p := &condParser{text: text}
+	if err := p.lex(); err != nil {
+		return Condition{}, fmt.Errorf("condition %q: %w", text, err)
+	}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return Condition{}, fmt.Errorf("condition %q: %w", text, err)
+	}
+	return Condition{text: text, root: root, names: p.names}, nil
+}
+
+// String returns the condition as written.
+func (c Condition) String() string {
+	return c.text
+}
+
+// Attributes returns the names of the attributes the condition reads, sorted.
+func (c Condition) Attributes() []string {
+	return slices.Clone(c.names)
+}
+
+// Eval reports whether the condition holds for attrs. Every attribute the condition
+// reads must be in attrs, whichever branch decides the result.
+func (c Condition) Eval(attrs Attributes) (bool, error) {
+	if c.root == nil {
+		return false, fmt.Errorf("empty condition")
+	}
+	for _, name := range c.names {
+		if _, ok := attrs[name]; !ok {
+			return false, fmt.Errorf("condition %q: attribute %q is not set", c.text, name)
+		}
+	}
+	ok, err := c.root.eval(attrs)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", c.text, err)
+	}
+	return ok, nil
+}
+
+type condNode interface {
+	eval(attrs Attributes) (bool, error)
+}
+
+type (
+	orNode   struct{ left, right condNode }
+	andNode  struct{ left, right condNode }
+	notNode  struct{ operand condNode }
+	boolNode struct{ name string }
+	cmpNode  struct {
+		name  string
+		op    string
+		value any
+	}
+	inNode struct {
+		name  string
+		items []inItem
+	}
+	inItem struct {
+		lo, hi any // hi is nil for a single value
+	}
+)
+
+func (n orNode) eval(attrs Attributes) (bool, error) {
+	l, err := n.left.eval(attrs)
+	if err != nil || l {
+		return l, err
+	}
+	return n.right.eval(attrs)
+}
+
+func (n andNode) eval(attrs Attributes) (bool, error) {
+	l, err := n.left.eval(attrs)
+	if err != nil || !l {
+		return false, err
+	}
+	return n.right.eval(attrs)
+}
+
+func (n notNode) eval(attrs Attributes) (bool, error) {
+	v, err := n.operand.eval(attrs)
+	return !v, err
+}
+
+func (n boolNode) eval(attrs Attributes) (bool, error) {
+	b, ok := attrs[n.name].(bool)
+	if !ok {
+		return false, fmt.Errorf("attribute %q is %v, not a bool", n.name, attrs[n.name])
+	}
+	return b, nil
+}
+
+func (n cmpNode) eval(attrs Attributes) (bool, error) {
+	return compare(n.name, attrs[n.name], n.op, n.value)
+}
+
+func (n inNode) eval(attrs Attributes) (bool, error) {
+	v := attrs[n.name]
+	for _, item := range n.items {
+		var ok bool
+		var err error
+		if item.hi == nil {
+			ok, err = compare(n.name, v, "==", item.lo)
+		} else {
+			ok, err = compare(n.name, v, ">=", item.lo)
+			if ok && err == nil {
+				ok, err = compare(n.name, v, "<=", item.hi)
+			}
+		}
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// compare applies op to an attribute value and a literal of the condition.
+func compare(name string, v any, op string, lit any) (bool, error) {
+	if a, ok := toNumber(v); ok {
+		b, ok := lit.(float64)
+		if !ok {
+			return false, fmt.Errorf("attribute %q is a number, compared with %v", name, describeValue(lit))
+		}
+		switch op {
+		case "==":
+			return a == b, nil
+		case "!=":
+			return a != b, nil
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		case ">=":
+			return a >= b, nil
+		}
+	}
+	switch v.(type) {
+	case string, bool:
+		if op != "==" && op != "!=" {
+			return false, fmt.Errorf("attribute %q is %v, which has no order", name, describeValue(v))
+		}
+		if fmt.Sprintf("%T", v) != fmt.Sprintf("%T", lit) {
+			return false, fmt.Errorf("attribute %q is %v, compared with %v", name, describeValue(v), describeValue(lit))
+		}
+		return (v == lit) == (op == "=="), nil
+	}
+	return false, fmt.Errorf("attribute %q has unsupported value %v (%T)", name, v, v)
+}
+
+// toNumber converts an attribute value of any int or float type.
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// condToken is a token of a condition: an identifier or keyword, a number, a string
+// or an operator.
+type condToken struct {
+	kind byte // 'i' identifier, 'n' number, 's' string, 'o' operator
+	text string
+}
+
+type condParser struct {
+	text  string
+	toks  []condToken
+	pos   int
+	names []string
+}
+
+var condOperators = []string{"==", "!=", "<=", ">=", "..", "<", ">", "(", ")", "[", "]", ","}
+
+func (p *condParser) lex() error {
+	s := p.text
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			p.toks = append(p.toks, condToken{'i', s[i:j]})
+			i = j
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || (s[j] == '.' && !strings.HasPrefix(s[j:], ".."))) {
+				j++
+			}
+			p.toks = append(p.toks, condToken{'n', s[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], s[i])
+			if j < 0 {
+				return fmt.Errorf("unclosed string at %d", i)
+			}
+			p.toks = append(p.toks, condToken{'s', s[i+1 : i+1+j]})
+			i += j + 2
+		default:
+			op := ""
+			for _, o := range condOperators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			p.toks = append(p.toks, condToken{'o', op})
+			i += len(op)
+		}
+	}
+	if len(p.toks) == 0 {
+		return fmt.Errorf("empty condition")
+	}
+	return nil
+}
+
+func (p *condParser) peek() condToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return condToken{}
+}
+
+func (p *condParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == 'i' && t.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) operator(op string) bool {
+	if t := p.peek(); t.kind == 'o' && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword("or") {
+		var right condNode
+		right, err = p.parseAnd()
+		left = orNode{left, right}
+	}
+	return left, err
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.keyword("and") {
+		var right condNode
+		right, err = p.parseNot()
+		left = andNode{left, right}
+	}
+	return left, err
+}
+
+func (p *condParser) parseNot() (condNode, error) {
+	if p.keyword("not") {
+		operand, err := p.parseNot()
+		return notNode{operand}, err
+	}
+	return p.parseTerm()
+}
+
+func (p *condParser) parseTerm() (condNode, error) {
+	if p.operator("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.operator(")") {
+			return nil, fmt.Errorf("missing %q", ")")
+		}
+		return n, nil
+	}
+	t := p.peek()
+	if t.kind != 'i' || isCondKeyword(t.text) {
+		return nil, fmt.Errorf("expected an attribute, got %q", t.text)
+	}
+	p.pos++
+	if !slices.Contains(p.names, t.text) {
+		p.names = append(p.names, t.text)
+		slices.Sort(p.names)
+	}
+	if p.keyword("in") {
+		items, err := p.parseItems()
+		return inNode{name: t.text, items: items}, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.operator(op) {
+			v, err := p.parseValue()
+			return cmpNode{name: t.text, op: op, value: v}, err
+		}
+	}
+	return boolNode{name: t.text}, nil
+}
+
+// parseItems parses the set of "in": a range, or a bracketed list of values and
+// ranges.
+func (p *condParser) parseItems() ([]inItem, error) {
+	if !p.operator("[") {
+		item, err := p.parseItem()
+		return []inItem{item}, err
+	}
+	var items []inItem
+	for {
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.operator("]") {
+			return items, nil
+		}
+		if !p.operator(",") {
+			return nil, fmt.Errorf("expected %q or %q, got %q", ",", "]", p.peek().text)
+		}
+	}
+}
+
+func (p *condParser) parseItem() (inItem, error) {
+	lo, err := p.parseValue()
+	if err != nil {
+		return inItem{}, err
+	}
+	if !p.operator("..") {
+		return inItem{lo: lo}, nil
+	}
+	hi, err := p.parseValue()
+	if err != nil {
+		return inItem{}, err
+	}
+	l, lok := lo.(float64)
+	h, hok := hi.(float64)
+	if !lok || !hok || l > h {
+		return inItem{}, fmt.Errorf("invalid range %v..%v", describeValue(lo), describeValue(hi))
+	}
+	return inItem{lo: lo, hi: hi}, nil
+}
+
+func (p *condParser) parseValue() (any, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t.kind == 'n':
+		return strconv.ParseFloat(t.text, 64)
+	case t.kind == 's':
+		return t.text, nil
+	case t.kind == 'i' && t.text == "true":
+		return true, nil
+	case t.kind == 'i' && t.text == "false":
+		return false, nil
+	}
+	if t.text == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	return nil, fmt.Errorf("expected a value, got %q", t.text)
+}
+
+func isCondKeyword(word string) bool {
+	switch word {
+	case "and", "or", "not", "in", "true", "false":
+		return true
+	}
+	return false
+}
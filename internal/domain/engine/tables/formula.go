@@ -0,0 +1,134 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Galdoba/cepheus/internal/domain/engine/dice"
+)
+
+// Formula is a "formula" asset: a dice roll such as "1d6-3" or an equation such as
+// "T = B * (1-A)^0.25 * (1+E)" with the meaning of its Variables. Documents with
+// one roll per case ("Star": "1d6-2", ...) use Formulas instead of Formula.
+type Formula struct {
+	Name      string            `json:"name"`
+	Formula   string            `json:"formula,omitempty"`
+	Formulas  map[string]string `json:"formulas,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	Notes     string            `json:"notes,omitempty"`
+}
+
+// AssetName returns the name of the formula.
+func (f Formula) AssetName() string {
+	return f.Name
+}
+
+// Validate checks that the formula has a name and either Formula or Formulas.
+func (f Formula) Validate() error {
+	if f.Name == "" {
+		return errors.New("formula name cannot be empty")
+	}
+	if (f.Formula == "") == (len(f.Formulas) == 0) {
+		return fmt.Errorf("formula %q must have either formula or formulas", f.Name)
+	}
+	for k, v := range f.Formulas {
+		if v == "" {
+			return fmt.Errorf("formula %q has empty case %q", f.Name, k)
+		}
+	}
+	return nil
+}
+
+// IsRoll reports whether Formula is a dice expression rather than an equation.
+func (f Formula) IsRoll() bool {
+	return dice.ValidateExpression(f.Formula) == nil
+}
+
+func decodeFormula(data []byte) (Asset, error) {
+	f := Formula{}
+	if err := decodeStrict(data, &f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// FormulaCollection is a "formula_collection" asset: equations for the cases of one
+// quantity, e.g. the equatorial temperature below and above 35 degrees of tilt.
+type FormulaCollection struct {
+	Name     string                 `json:"name"`
+	Formulas map[string]FormulaCase `json:"formulas"`
+}
+
+// FormulaCase is one equation of a FormulaCollection.
+type FormulaCase struct {
+	Formula string `json:"formula"`
+	Notes   string `json:"notes,omitempty"`
+}
+
+// AssetName returns the name of the collection.
+func (fc FormulaCollection) AssetName() string {
+	return fc.Name
+}
+
+// Validate checks that the collection has a name and no empty equation.
+func (fc FormulaCollection) Validate() error {
+	if fc.Name == "" {
+		return errors.New("formula collection name cannot be empty")
+	}
+	if len(fc.Formulas) == 0 {
+		return fmt.Errorf("formula collection %q has no formulas", fc.Name)
+	}
+	for k, c := range fc.Formulas {
+		if c.Formula == "" {
+			return fmt.Errorf("formula collection %q has empty formula %q", fc.Name, k)
+		}
+	}
+	return nil
+}
+
+func decodeFormulaCollection(data []byte) (Asset, error) {
+	fc := FormulaCollection{}
+	if err := decodeStrict(data, &fc); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// FormulaTable is a "formula_table" asset: the roll to make for each case, e.g. the
+// number of moons by planet size. Values are dice expressions or constants.
+type FormulaTable struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Data        map[string]string `json:"data"`
+	Notes       string            `json:"notes,omitempty"`
+}
+
+// AssetName returns the name of the table.
+func (ft FormulaTable) AssetName() string {
+	return ft.Name
+}
+
+// Validate checks that the table has a name and no empty case.
+func (ft FormulaTable) Validate() error {
+	if ft.Name == "" {
+		return errors.New("formula table name cannot be empty")
+	}
+	if len(ft.Data) == 0 {
+		return fmt.Errorf("formula table %q has no entries", ft.Name)
+	}
+	for k, v := range ft.Data {
+		if v == "" {
+			return fmt.Errorf("formula table %q has empty value for %q", ft.Name, k)
+		}
+	}
+	return nil
+}
+
+func decodeFormulaTable(data []byte) (Asset, error) {
+	ft := FormulaTable{}
+	if err := decodeStrict(data, &ft); err != nil {
+		return nil, err
+	}
+	return ft, nil
+}
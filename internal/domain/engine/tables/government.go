@@ -0,0 +1,79 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GovernmentTable is a "government_table" asset: BaseRoll picks a row with the
+// government code, its name and the roll for the law level.
+type GovernmentTable struct {
+	Name     string                   `json:"name"`
+	BaseRoll string                   `json:"base_roll"`
+	Columns  []string                 `json:"columns,omitempty"`
+	Data     map[string]GovernmentRow `json:"data"`
+}
+
+// GovernmentRow is a row of a GovernmentTable. Code and LawLevel are kept as
+// written: a code, a roll such as "2d6-1", or "Normal" to roll as usual.
+type GovernmentRow struct {
+	Code       string `json:"code"`
+	Government string `json:"government"`
+	LawLevel   string `json:"law_level"`
+}
+
+// AssetName returns the name of the table.
+func (gt GovernmentTable) AssetName() string {
+	return gt.Name
+}
+
+// governmentSchema checks the rows of a government table as structured entries.
+var governmentSchema = &Schema{
+	Type:     "object",
+	Required: []string{"code", "government", "law_level"},
+	Properties: map[string]*Schema{
+		"code":       {Type: "string"},
+		"government": {Type: "string"},
+		"law_level":  {Type: "string"},
+	},
+}
+
+// Validate checks the rows like a GameTable rolled with BaseRoll.
+func (gt GovernmentTable) Validate() error {
+	if gt.Name == "" {
+		return errors.New("table name cannot be empty")
+	}
+	for key, row := range gt.Data {
+		if row.Code == "" || row.Government == "" {
+			return fmt.Errorf("table %q row %q has no code or government", gt.Name, key)
+		}
+	}
+	return gt.Table().Validate()
+}
+
+// Lookup returns the row covering a total of BaseRoll.
+func (gt GovernmentTable) Lookup(index int) (GovernmentRow, bool) {
+	row, ok := gt.Data[gt.Table().keyAt(index)]
+	return row, ok
+}
+
+// Table returns the government table as a GameTable with structured rows, labelled
+// by government, so it can be rolled in a Collection.
+func (gt GovernmentTable) Table() GameTable {
+	t := New(gt.Name, gt.BaseRoll, make(map[string]string, len(gt.Data)))
+	t.Label, t.Schema = "government", governmentSchema
+	t.Rows = make(map[string]map[string]any, len(gt.Data))
+	for key, row := range gt.Data {
+		t.Rows[key] = map[string]any{"code": row.Code, "government": row.Government, "law_level": row.LawLevel}
+		t.Data[key] = row.Government
+	}
+	return t
+}
+
+func decodeGovernmentTable(data []byte) (Asset, error) {
+	gt := GovernmentTable{}
+	if err := decodeStrict(data, &gt); err != nil {
+		return nil, err
+	}
+	return gt, nil
+}
@@ -0,0 +1,212 @@
+package tables
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Galdoba/cepheus/assets"
+)
+
+const (
+	// BuiltinLayer is the name of the layer of the assets embedded in the binary.
+	BuiltinLayer = "builtin"
+	// patchField marks a document that patches the asset of the same name in a lower
+	// layer instead of replacing it.
+	patchField = "patch"
+)
+
+// Layer is a set of asset documents stacked on the layers below it, e.g. a campaign
+// directory with house rules on top of the built-in assets. Name is reported as the
+// provenance of the assets it defines.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// Builtin returns the layer of the assets embedded in the binary.
+func Builtin() Layer {
+	return Layer{Name: BuiltinLayer, FS: assets.FS}
+}
+
+// DirLayer returns the layer of a directory on disk, named after the directory.
+func DirLayer(dir string) Layer {
+	return Layer{Name: dir, FS: os.DirFS(dir)}
+}
+
+// AssetSource is a document that contributed to an asset.
+type AssetSource struct {
+	Layer string
+	Path  string
+}
+
+// String returns the source as "layer:path".
+func (src AssetSource) String() string {
+	return src.Layer + ":" + src.Path
+}
+
+// LoadDefault loads the built-in assets with the override layers on top, in order.
+func LoadDefault(overrides ...Layer) (*Catalogue, error) {
+	return LoadLayers(append([]Layer{Builtin()}, overrides...)...)
+}
+
+// LoadLayers loads the ".json" documents of the layers, from the bottom up, into one
+// Catalogue. Documents are matched by "name" across layers:
+//
+//   - a document whose name is new adds an asset, with its path as ID;
+//   - a document with the name of a loaded asset replaces it and keeps its ID;
+//   - a document with "patch": true is merged into the loaded asset of its name as a
+//     JSON merge patch (RFC 7396): objects merge key by key and null removes a key,
+//     so a patch can change a few entries of a table. It cannot change the type.
+//
+// The result of every document is decoded and validated. A name may appear once per
+// layer. All documents are loaded and every failure is reported, with its layer and
+// path, in one joined error.
+func LoadLayers(layers ...Layer) (*Catalogue, error) {
+	c := &Catalogue{assets: map[string]catalogued{}, names: map[string]string{}}
+	var errs []error
+	for _, layer := range layers {
+		if layer.FS == nil {
+			errs = append(errs, fmt.Errorf("layer %q has no file system", layer.Name))
+			continue
+		}
+		names := map[string]string{}
+		err := fs.WalkDir(layer.FS, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || path.Ext(p) != assetExt {
+				return nil
+			}
+			src := AssetSource{Layer: layer.Name, Path: p}
+			name, err := c.add(src, layer.FS)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("asset %q: %w", src, err))
+				return nil
+			}
+			if prev, ok := names[name]; ok {
+				errs = append(errs, fmt.Errorf("asset %q: name %q is already used by %q in the same layer", src, name, prev))
+			}
+			names[name] = p
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk layer %q: %w", layer.Name, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to load catalogue: %w", errors.Join(errs...))
+	}
+	return c, nil
+}
+
+// add loads one document of a layer and returns its name.
+func (c *Catalogue) add(src AssetSource, fsys fs.FS) (string, error) {
+	data, err := fs.ReadFile(fsys, src.Path)
+	if err != nil {
+		return "", err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("failed to unmarshal asset: %w", err)
+	}
+	name, patch, err := documentHeader(fields)
+	if err != nil {
+		return "", err
+	}
+	fields = withoutField(fields, patchField)
+	id, exists := c.names[name]
+	var info AssetInfo
+	switch {
+	case patch && !exists:
+		return name, fmt.Errorf("patch of %q has no asset to patch", name)
+	case patch:
+		base := c.assets[id]
+		if _, ok := fields["type"]; ok {
+			typ, err := assetType(fields)
+			if err != nil {
+				return name, err
+			}
+			if typ != base.info.Type {
+				return name, fmt.Errorf("patch of %q changes its type from %s to %s", name, base.info.Type, typ)
+			}
+		}
+		fields = mergePatch(base.doc, fields)
+		info = base.info
+		info.Patches = append(slices.Clone(base.info.Patches), src)
+	case exists:
+		// The replaced asset goes with every document that made it.
+		base := c.assets[id]
+		info = AssetInfo{ID: id, Layer: src.Layer, Path: src.Path}
+		info.Replaced = append(slices.Clone(base.info.Replaced), base.info.source())
+		info.Replaced = append(info.Replaced, base.info.Patches...)
+	default:
+		id = strings.TrimSuffix(src.Path, assetExt)
+		if other, ok := c.assets[id]; ok {
+			return name, fmt.Errorf("asset ID %q is already used by %q", id, other.info.Name)
+		}
+		info = AssetInfo{ID: id, Layer: src.Layer, Path: src.Path}
+	}
+	typ, asset, err := decodeFields(fields)
+	if err != nil {
+		return name, err
+	}
+	info.Name, info.Type = asset.AssetName(), typ
+	c.assets[id] = catalogued{info: info, asset: asset, doc: fields}
+	c.names[name] = id
+	return name, nil
+}
+
+// documentHeader reads the name of a document and whether it is a patch.
+func documentHeader(fields map[string]json.RawMessage) (string, bool, error) {
+	var name string
+	if raw, ok := fields["name"]; ok {
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return "", false, fmt.Errorf("failed to read asset name: %w", err)
+		}
+	}
+	if name == "" {
+		return "", false, errors.New("asset has no name")
+	}
+	var patch bool
+	if raw, ok := fields[patchField]; ok {
+		if err := json.Unmarshal(raw, &patch); err != nil {
+			return "", false, fmt.Errorf("failed to read %q: %w", patchField, err)
+		}
+	}
+	return name, patch, nil
+}
+
+// mergePatch applies a JSON merge patch (RFC 7396) to the fields of a document.
+func mergePatch(target, patch map[string]json.RawMessage) map[string]json.RawMessage {
+	out := maps.Clone(target)
+	if out == nil {
+		out = map[string]json.RawMessage{}
+	}
+	for k, p := range patch {
+		if string(p) == "null" {
+			delete(out, k)
+			continue
+		}
+		var pObj map[string]json.RawMessage
+		if err := json.Unmarshal(p, &pObj); err != nil || pObj == nil {
+			out[k] = p
+			continue
+		}
+		var tObj map[string]json.RawMessage
+		_ = json.Unmarshal(out[k], &tObj) // a missing or non-object target merges as {}
+		merged, err := json.Marshal(mergePatch(tObj, pObj))
+		if err != nil {
+			out[k] = p
+			continue
+		}
+		out[k] = merged
+	}
+	return out
+}
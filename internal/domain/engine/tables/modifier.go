@@ -0,0 +1,307 @@
+package tables
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// modifierGroupSuffix ends the name of the grouped modifier fields of a modifier
+// table, e.g. "size_modifiers" for the group "size".
+const modifierGroupSuffix = "_modifiers"
+
+// ModifierTable is a "modifier_table" asset: the DMs that apply to BaseRoll under
+// their conditions. BaseRoll may be a note rather than an expression ("1d6 (see the
+// hydrographics table)"), so it is not validated.
+//
+// The documents hold their modifiers in "modifiers" or in groups named "<group>_modifiers";
+// all of them are read into Modifiers, keyed as written and tagged with their Group.
+type ModifierTable struct {
+	Name        string              `json:"name"`
+	BaseRoll    string              `json:"base_roll,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Notes       string              `json:"notes,omitempty"`
+	Modifiers   map[string]Modifier `json:"-"`
+}
+
+// Modifier is a DM and the condition, as written, under which it applies. When is
+// the condition in the language of ParseCondition; a modifier without it is only
+// documentation and cannot be applied.
+type Modifier struct {
+	Group     string `json:"-"`
+	Condition string `json:"condition"`
+	When      string `json:"when,omitempty"`
+	Value     int    `json:"modifier"`
+}
+
+// AppliedModifier is a modifier whose condition held, and where it came from.
+type AppliedModifier struct {
+	Table     string
+	Key       string
+	Condition string
+	When      string
+	Value     int
+}
+
+// Explanation lists the modifiers that applied to a roll, in the order they were
+// evaluated, and their Total.
+type Explanation struct {
+	Applied []AppliedModifier
+	Total   int
+}
+
+// String returns the applied conditions and the total, e.g.
+// "Atmosphere code 6 or 7: +1; Planet size 15(F)-19(K): +1; DM +2".
+func (e Explanation) String() string {
+	parts := make([]string, 0, len(e.Applied)+1)
+	for _, am := range e.Applied {
+		parts = append(parts, fmt.Sprintf("%s: %+d", am.Condition, am.Value))
+	}
+	parts = append(parts, fmt.Sprintf("DM %+d", e.Total))
+	return strings.Join(parts, "; ")
+}
+
+// add evaluates a modifier and records it if its condition holds.
+func (e *Explanation) add(table, key string, mod Modifier, attrs Attributes) error {
+	if mod.When == "" {
+		return fmt.Errorf("modifier table %q: modifier %q has no %q condition to evaluate", table, key, "when")
+	}
+	cond, err := ParseCondition(mod.When)
+	if err != nil {
+		return fmt.Errorf("modifier table %q: modifier %q: %w", table, key, err)
+	}
+	ok, err := cond.Eval(attrs)
+	if err != nil {
+		return fmt.Errorf("modifier table %q: modifier %q: %w", table, key, err)
+	}
+	if ok {
+		e.Applied = append(e.Applied, AppliedModifier{Table: table, Key: key, Condition: mod.Condition, When: mod.When, Value: mod.Value})
+		e.Total += mod.Value
+	}
+	return nil
+}
+
+// validateWhen checks that a modifier's When, if any, parses.
+func (mod Modifier) validateWhen() error {
+	if mod.When == "" {
+		return nil
+	}
+	_, err := ParseCondition(mod.When)
+	return err
+}
+
+// UnmarshalJSON reads the plain fields and merges "modifiers" and every
+// "<group>_modifiers" field into Modifiers. A key used in two groups is an error.
+func (mt *ModifierTable) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	type plain ModifierTable
+	head := map[string]json.RawMessage{}
+	all := map[string]Modifier{}
+	for field, raw := range fields {
+		group, ok := strings.CutSuffix(field, modifierGroupSuffix)
+		if field == "modifiers" {
+			group, ok = "", true
+		}
+		if !ok {
+			head[field] = raw
+			continue
+		}
+		mods := map[string]Modifier{}
+		if err := decodeStrict(raw, &mods); err != nil {
+			return fmt.Errorf("field %q: %w", field, err)
+		}
+		for key, mod := range mods {
+			if _, dup := all[key]; dup {
+				return fmt.Errorf("modifier %q is defined twice", key)
+			}
+			mod.Group = group
+			all[key] = mod
+		}
+	}
+	body, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	p := plain{}
+	if err := decodeStrict(body, &p); err != nil {
+		return err
+	}
+	p.Modifiers = all
+	*mt = ModifierTable(p)
+	return nil
+}
+
+// AssetName returns the name of the table.
+func (mt ModifierTable) AssetName() string {
+	return mt.Name
+}
+
+// Validate checks that the table has a name and that every modifier has a condition
+// and a When that parses, if it has one.
+func (mt ModifierTable) Validate() error {
+	if mt.Name == "" {
+		return errors.New("modifier table name cannot be empty")
+	}
+	if len(mt.Modifiers) == 0 {
+		return fmt.Errorf("modifier table %q has no modifiers", mt.Name)
+	}
+	for key, mod := range mt.Modifiers {
+		if mod.Condition == "" {
+			return fmt.Errorf("modifier table %q: modifier %q has no condition", mt.Name, key)
+		}
+		if err := mod.validateWhen(); err != nil {
+			return fmt.Errorf("modifier table %q: modifier %q: %w", mt.Name, key, err)
+		}
+	}
+	return nil
+}
+
+// Apply evaluates the When of every modifier, by key, against attrs and sums the DMs
+// of those that hold. Every modifier must have a When, and attrs every attribute
+// they read.
+func (mt ModifierTable) Apply(attrs Attributes) (Explanation, error) {
+	e := Explanation{}
+	for _, key := range slices.Sorted(maps.Keys(mt.Modifiers)) {
+		if err := e.add(mt.Name, key, mt.Modifiers[key], attrs); err != nil {
+			return Explanation{}, err
+		}
+	}
+	return e, nil
+}
+
+// Groups returns the groups of the modifiers, sorted; "" stands for "modifiers".
+func (mt ModifierTable) Groups() []string {
+	groups := map[string]bool{}
+	for _, mod := range mt.Modifiers {
+		groups[mod.Group] = true
+	}
+	return slices.Sorted(maps.Keys(groups))
+}
+
+func decodeModifierTable(data []byte) (Asset, error) {
+	mt := ModifierTable{}
+	if err := json.Unmarshal(data, &mt); err != nil {
+		return nil, err
+	}
+	return mt, nil
+}
+
+// ThresholdTable is a "threshold_table" asset: the result of BaseRoll, modified by
+// the DMs whose conditions apply, is the first threshold whose bounds hold the total.
+type ThresholdTable struct {
+	Name       string      `json:"name"`
+	BaseRoll   string      `json:"base_roll"`
+	Thresholds []Threshold `json:"thresholds"`
+	Modifiers  []Modifier  `json:"modifiers,omitempty"`
+}
+
+// Threshold is a result of a ThresholdTable for totals within [Min, Max]. A nil
+// bound is open.
+type Threshold struct {
+	Min    *int   `json:"min,omitempty"`
+	Max    *int   `json:"max,omitempty"`
+	Result string `json:"result"`
+}
+
+// Holds reports whether total is within the bounds of the threshold.
+func (th Threshold) Holds(total int) bool {
+	return (th.Min == nil || total >= *th.Min) && (th.Max == nil || total <= *th.Max)
+}
+
+// AssetName returns the name of the table.
+func (tt ThresholdTable) AssetName() string {
+	return tt.Name
+}
+
+// Validate checks the base roll and that the thresholds are ascending, do not
+// overlap and have results.
+func (tt ThresholdTable) Validate() error {
+	if tt.Name == "" {
+		return errors.New("threshold table name cannot be empty")
+	}
+	if err := validateExpression(tt.BaseRoll); err != nil {
+		return fmt.Errorf("threshold table %q: %w", tt.Name, err)
+	}
+	if len(tt.Thresholds) == 0 {
+		return fmt.Errorf("threshold table %q has no thresholds", tt.Name)
+	}
+	for i, th := range tt.Thresholds {
+		if th.Result == "" {
+			return fmt.Errorf("threshold table %q: threshold %d has no result", tt.Name, i)
+		}
+		if th.Min != nil && th.Max != nil && *th.Min > *th.Max {
+			return fmt.Errorf("threshold table %q: threshold %q has min %d above max %d", tt.Name, th.Result, *th.Min, *th.Max)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := tt.Thresholds[i-1]
+		if prev.Max == nil || th.Min == nil || *th.Min <= *prev.Max {
+			return fmt.Errorf("threshold table %q: threshold %q overlaps %q", tt.Name, th.Result, prev.Result)
+		}
+	}
+	for i, mod := range tt.Modifiers {
+		if err := mod.validateWhen(); err != nil {
+			return fmt.Errorf("threshold table %q: modifier %d: %w", tt.Name, i, err)
+		}
+	}
+	return nil
+}
+
+// Apply evaluates the modifiers of the table against attrs, as ModifierTable.Apply
+// does. Their keys are their indexes.
+func (tt ThresholdTable) Apply(attrs Attributes) (Explanation, error) {
+	e := Explanation{}
+	for i, mod := range tt.Modifiers {
+		if err := e.add(tt.Name, strconv.Itoa(i), mod, attrs); err != nil {
+			return Explanation{}, err
+		}
+	}
+	return e, nil
+}
+
+// Roll rolls BaseRoll with the DMs that apply to attrs and mods, and returns the
+// result of the threshold holding the total.
+func (tt ThresholdTable) Roll(roller TableRoller, attrs Attributes, mods ...int) (string, Explanation, error) {
+	if roller == nil {
+		return "", Explanation{}, fmt.Errorf("nil roller provided")
+	}
+	e, err := tt.Apply(attrs)
+	if err != nil {
+		return "", Explanation{}, err
+	}
+	total, err := roller.Roll(tt.BaseRoll, append(slices.Clone(mods), e.Total)...)
+	if err != nil {
+		return "", Explanation{}, fmt.Errorf("roll on threshold table %q failed: %w", tt.Name, err)
+	}
+	result, ok := tt.Lookup(total)
+	if !ok {
+		return "", Explanation{}, fmt.Errorf("threshold table %q has no result for %d", tt.Name, total)
+	}
+	return result, e, nil
+}
+
+// Lookup returns the result of the threshold holding total.
+func (tt ThresholdTable) Lookup(total int) (string, bool) {
+	for _, th := range tt.Thresholds {
+		if th.Holds(total) {
+			return th.Result, true
+		}
+	}
+	return "", false
+}
+
+func decodeThresholdTable(data []byte) (Asset, error) {
+	tt := ThresholdTable{}
+	if err := decodeStrict(data, &tt); err != nil {
+		return nil, err
+	}
+	return tt, nil
+}
@@ -0,0 +1,275 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxResolveDepth bounds how deep table references nest while a row is resolved. A
+// table may refer to itself ("roll twice on this table"), so loops are caught by
+// depth rather than forbidden.
+const MaxResolveDepth = 32
+
+// ErrResolveLoop fails a roll whose table references nest deeper than MaxResolveDepth.
+var ErrResolveLoop = errors.New("table reference loop")
+
+// Step is a placeholder resolved while rolling a row, in the order they were
+// resolved. A reference is followed by the steps of the row it rolled.
+type Step struct {
+	// Depth is 0 for the placeholders of the rolled row, 1 for those of the rows
+	// it refers to, and so on.
+	Depth int
+	// Table holds the entry with the placeholder.
+	Table string
+	// Placeholder is as written, e.g. "{2d6-1}" or "{@Government}".
+	Placeholder string
+	// Value replaced the placeholder: the total of a roll or the text of a row.
+	Value string
+}
+
+// segment is a piece of a result text: literal text, an inline roll or a reference.
+type segment struct {
+	text string
+	expr string
+	ref  string
+}
+
+// placeholder returns the segment as written.
+func (s segment) placeholder() string {
+	if s.ref != "" {
+		return "{@" + s.ref + "}"
+	}
+	return "{" + s.expr + "}"
+}
+
+// parseTemplate splits a result text into its segments. "{expr}" is an inline
+// roll, "{@Name}" a reference to a table, and "{{" and "}}" are literal braces.
+func parseTemplate(s string) ([]segment, error) {
+	var segs []segment
+	var lit strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '{' && strings.HasPrefix(s[i:], "{{"), c == '}' && strings.HasPrefix(s[i:], "}}"):
+			lit.WriteByte(c)
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("unmatched %q at %d in %q", "}", i, s)
+		case c == '{':
+			end := strings.IndexAny(s[i+1:], "{}")
+			if end < 0 || s[i+1+end] != '}' {
+				return nil, fmt.Errorf("unclosed %q at %d in %q", "{", i, s)
+			}
+			body := strings.TrimSpace(s[i+1 : i+1+end])
+			seg := segment{expr: body}
+			if ref, ok := strings.CutPrefix(body, "@"); ok {
+				seg = segment{ref: strings.TrimSpace(ref)}
+			}
+			if seg.expr == "" && seg.ref == "" {
+				return nil, fmt.Errorf("empty placeholder at %d in %q", i, s)
+			}
+			if lit.Len() > 0 {
+				segs = append(segs, segment{text: lit.String()})
+				lit.Reset()
+			}
+			segs = append(segs, seg)
+			i += end + 1
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	if lit.Len() > 0 {
+		segs = append(segs, segment{text: lit.String()})
+	}
+	return segs, nil
+}
+
+// templates returns the texts of the table that may hold placeholders: the string
+// entries and every string in the fields of the structured ones.
+func (t GameTable) templates() []string {
+	var texts []string
+	for key, text := range t.Data {
+		if fields, ok := t.Rows[key]; ok {
+			texts = appendStrings(texts, fields)
+			continue
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+func appendStrings(texts []string, v any) []string {
+	switch v := v.(type) {
+	case string:
+		texts = append(texts, v)
+	case []any:
+		for _, item := range v {
+			texts = appendStrings(texts, item)
+		}
+	case map[string]any:
+		for _, item := range v {
+			texts = appendStrings(texts, item)
+		}
+	}
+	return texts
+}
+
+// validateTemplates checks the placeholders of the table: their syntax and the
+// expressions of the inline rolls. References are checked by the Collection.
+func (t GameTable) validateTemplates() error {
+	for _, text := range t.templates() {
+		segs, err := parseTemplate(text)
+		if err != nil {
+			return fmt.Errorf("table %q: %w", t.Name, err)
+		}
+		for _, seg := range segs {
+			if seg.expr == "" {
+				continue
+			}
+			if err := validateExpression(seg.expr); err != nil {
+				return fmt.Errorf("table %q: inline roll %q is not parseable: %w", t.Name, seg.placeholder(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// references returns the names of the tables the table refers to, sorted.
+func (t GameTable) references() []string {
+	var refs []string
+	for _, text := range t.templates() {
+		segs, _ := parseTemplate(text) // checked by Validate
+		for _, seg := range segs {
+			if seg.ref != "" && !slices.Contains(refs, seg.ref) {
+				refs = append(refs, seg.ref)
+			}
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// resolve replaces the placeholders of a rolled row. chain holds the tables being
+// resolved above it, the table of the row included; references roll with attrs.
+func (tc *Collection) resolve(roller TableRoller, table GameTable, row Row, attrs Attributes, chain []string) (Row, error) {
+	if len(chain) > MaxResolveDepth {
+		return Row{}, loopError(chain)
+	}
+	if !row.Structured() {
+		text, err := tc.resolveText(roller, table.Name, row.Text, attrs, chain, &row.Trace)
+		if err != nil {
+			return Row{}, err
+		}
+		row.Text = text
+		return row, nil
+	}
+	fields, err := tc.resolveValue(roller, table.Name, row.Fields, attrs, chain, &row.Trace)
+	if err != nil {
+		return Row{}, err
+	}
+	row.Fields = fields.(map[string]any)
+	row.Text = table.rowText(row.Fields)
+	return row, nil
+}
+
+// resolveValue resolves every string of a field value.
+func (tc *Collection) resolveValue(roller TableRoller, table string, v any, attrs Attributes, chain []string, trace *[]Step) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return tc.resolveText(roller, table, v, attrs, chain, trace)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			r, err := tc.resolveValue(roller, table, item, attrs, chain, trace)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			r, err := tc.resolveValue(roller, table, v[k], attrs, chain, trace)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// resolveText resolves the placeholders of a text of an entry of table.
+func (tc *Collection) resolveText(roller TableRoller, table, text string, attrs Attributes, chain []string, trace *[]Step) (string, error) {
+	if !strings.ContainsAny(text, "{}") {
+		return text, nil
+	}
+	segs, err := parseTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("table %q: %w", table, err)
+	}
+	var b strings.Builder
+	for _, seg := range segs {
+		if seg.text != "" {
+			b.WriteString(seg.text)
+			continue
+		}
+		step := len(*trace)
+		*trace = append(*trace, Step{Depth: len(chain) - 1, Table: table, Placeholder: seg.placeholder()})
+		var value string
+		if seg.ref != "" {
+			sub, err := tc.roll(roller, seg.ref, nil, attrs, chain)
+			if errors.Is(err, ErrResolveLoop) {
+				return "", err
+			}
+			if err != nil {
+				return "", fmt.Errorf("resolve %s in table %q: %w", seg.placeholder(), table, err)
+			}
+			value = sub.Text
+			*trace = append(*trace, sub.Trace...)
+		} else {
+			value, err = rollInline(roller, seg.expr)
+			if err != nil {
+				return "", fmt.Errorf("resolve %s in table %q: %w", seg.placeholder(), table, err)
+			}
+		}
+		(*trace)[step].Value = value
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// rollInline rolls the expression of an inline roll: D66 and digit dice give their
+// digits, other expressions their total.
+func rollInline(roller TableRoller, expr string) (string, error) {
+	switch {
+	case expr == "d66" || expr == "D66":
+		return roller.D66(), nil
+	case isDigitExpression(expr):
+		dr, ok := roller.(DigitRoller)
+		if !ok {
+			return "", fmt.Errorf("roller cannot roll digit dice %q", expr)
+		}
+		return dr.Digits(expr)
+	}
+	total, err := roller.Roll(expr)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(total), nil
+}
+
+// loopError reports the first cycle of chain, or its depth when no table repeats.
+func loopError(chain []string) error {
+	for i, name := range chain {
+		if j := slices.Index(chain[i+1:], name); j >= 0 {
+			return fmt.Errorf("%w: %s", ErrResolveLoop, strings.Join(chain[i:i+j+2], " -> "))
+		}
+	}
+	return fmt.Errorf("%w: references nested deeper than %d", ErrResolveLoop, MaxResolveDepth)
+}
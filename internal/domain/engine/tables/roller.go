@@ -1,5 +1,7 @@
 package tables
 
+import "github.com/Galdoba/cepheus/internal/domain/engine/dice"
+
 // TableRoller is dice roller interface.
 // Implemented by /domain/engine/dice package. Can be inlemented elsewhere.
 type TableRoller interface {
@@ -10,3 +12,18 @@ type TableRoller interface {
 	// Provided mods might be handled separatly.
 	Roll(string, ...int) (int, error)
 }
+
+// DigitRoller is implemented by rollers that can roll digit dice ("D666", "D36").
+// Tables with a digit dice expression other than D66 need a roller implementing it.
+type DigitRoller interface {
+	// Digits rolls one die per digit of spec and returns the digits joined,
+	// applying mods to the dice in order.
+	Digits(spec string, mods ...int) (string, error)
+}
+
+// RangeRoller is implemented by rollers that can roll a total within a range while
+// keeping a dice trail, such as dice.Manager. Collection.Force needs it.
+type RangeRoller interface {
+	// RollBetween rolls the expression with the total, mods included, in [lo, hi].
+	RollBetween(expr string, lo, hi int, mods ...int) (int, dice.Result, error)
+}
@@ -0,0 +1,204 @@
+package tables
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Row is an entry of a table as Collection.Roll returns it: the Key that was hit and
+// the Text of the entry. A structured entry also has its Fields, as encoding/json
+// decodes an object, and its Text is its Label field. Trace lists the placeholders
+// resolved in the row, and Modifiers the DMs Collection.RollWith applied to the roll.
+type Row struct {
+	Key       string
+	Text      string
+	Fields    map[string]any
+	Trace     []Step
+	Modifiers Explanation
+}
+
+// String returns the text of the row, the value of a string entry.
+func (r Row) String() string {
+	return r.Text
+}
+
+// Structured reports whether the row has fields.
+func (r Row) Structured() bool {
+	return r.Fields != nil
+}
+
+// Get returns a field of a structured row.
+func (r Row) Get(field string) (any, bool) {
+	v, ok := r.Fields[field]
+	return v, ok
+}
+
+// Decode fills a typed struct, or anything encoding/json can unmarshal an object
+// into, with the fields of the row.
+func (r Row) Decode(v any) error {
+	if !r.Structured() {
+		return fmt.Errorf("row %q has no fields", r.Key)
+	}
+	data, err := json.Marshal(r.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal row %q: %w", r.Key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode row %q: %w", r.Key, err)
+	}
+	return nil
+}
+
+// NewRows creates a table with structured entries. A value is a string, as in New,
+// or anything that encodes as a JSON object: a map[string]any or a typed struct.
+// label names the field that is the text of a structured entry; without one, the
+// text is the entry as compact JSON.
+func NewRows(name, expression, label string, rows map[string]any) (GameTable, error) {
+	t := New(name, expression, make(map[string]string, len(rows)))
+	t.Label = label
+	for key, v := range rows {
+		if s, ok := v.(string); ok {
+			t.Data[key] = s
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return GameTable{}, fmt.Errorf("table %q entry %q: %w", name, key, err)
+		}
+		if err := t.setEntry(key, data); err != nil {
+			return GameTable{}, err
+		}
+	}
+	return t, nil
+}
+
+// row returns the entry at key.
+func (t GameTable) row(key string) Row {
+	r := Row{Key: key, Text: t.Data[key]}
+	if fields, ok := t.Rows[key]; ok {
+		r.Fields = maps.Clone(fields)
+	}
+	return r
+}
+
+// setEntry sets the entry at key from its JSON: a string or an object.
+func (t *GameTable) setEntry(key string, raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		t.Data[key] = s
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return fmt.Errorf("table %q entry %q must be a string or an object", t.Name, key)
+	}
+	if t.Rows == nil {
+		t.Rows = map[string]map[string]any{}
+	}
+	t.Rows[key] = fields
+	t.Data[key] = t.rowText(fields)
+	return nil
+}
+
+// rowText returns the text of a structured entry: its Label field, or the entry as
+// compact JSON. A label that is not a string is written as JSON.
+func (t GameTable) rowText(fields map[string]any) string {
+	if t.Label == "" {
+		return describeValue(fields)
+	}
+	v, ok := fields[t.Label]
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return describeValue(v)
+}
+
+// validateRows checks the schema and every entry against it. Rows must be entries
+// of Data.
+func (t GameTable) validateRows() error {
+	for key := range t.Rows {
+		if _, ok := t.Data[key]; !ok {
+			return fmt.Errorf("table %q has row %q without entry", t.Name, key)
+		}
+	}
+	if t.Schema == nil {
+		return nil
+	}
+	if err := t.Schema.Check(); err != nil {
+		return fmt.Errorf("table %q: %w", t.Name, err)
+	}
+	for key, text := range t.Data {
+		var v any = text
+		if fields, ok := t.Rows[key]; ok {
+			v = fields
+		}
+		if err := t.Schema.Validate(v); err != nil {
+			return fmt.Errorf("table %q entry %q does not match schema: %w", t.Name, key, err)
+		}
+	}
+	return nil
+}
+
+// tableDocument is the JSON of a table whose entries may be objects.
+type tableDocument struct {
+	Name       string                     `json:"name"`
+	Expression string                     `json:"expression"`
+	Data       map[string]json.RawMessage `json:"data"`
+	D66        bool                       `json:"d_66"`
+	Label      string                     `json:"label,omitempty"`
+	Schema     *Schema                    `json:"schema,omitempty"`
+}
+
+// MarshalJSON writes structured entries back as objects. A table of strings is
+// written as before.
+func (t GameTable) MarshalJSON() ([]byte, error) {
+	type plain GameTable
+	if len(t.Rows) == 0 {
+		return json.Marshal(plain(t))
+	}
+	doc := tableDocument{Name: t.Name, Expression: t.Expression, D66: t.D66, Label: t.Label, Schema: t.Schema}
+	doc.Data = make(map[string]json.RawMessage, len(t.Data))
+	for key, text := range t.Data {
+		var v any = text
+		if fields, ok := t.Rows[key]; ok {
+			v = fields
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("table %q entry %q: %w", t.Name, key, err)
+		}
+		doc.Data[key] = raw
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON reads a table whose entries are strings or objects.
+func (t *GameTable) UnmarshalJSON(data []byte) error {
+	return t.decode(data, json.Unmarshal)
+}
+
+// decode reads a table with unmarshal, which decides how strict it is.
+func (t *GameTable) decode(data []byte, unmarshal func([]byte, any) error) error {
+	doc := tableDocument{}
+	if err := unmarshal(data, &doc); err != nil {
+		return err
+	}
+	out := GameTable{Name: doc.Name, Expression: doc.Expression, D66: doc.D66, Label: doc.Label, Schema: doc.Schema}
+	if doc.Data != nil {
+		out.Data = make(map[string]string, len(doc.Data))
+	}
+	var errs []error
+	for key, raw := range doc.Data {
+		errs = append(errs, out.setEntry(key, raw))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	*t = out
+	return nil
+}
@@ -0,0 +1,144 @@
+package tables
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema the entries of a table are checked against:
+// type, enum, properties, required and additionalProperties for objects, items for
+// arrays, minimum and maximum for numbers and pattern for strings. Values are checked
+// as encoding/json decodes them into an interface.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Check validates the schema itself: known types and patterns that compile.
+func (s *Schema) Check() error {
+	return s.check("$")
+}
+
+func (s *Schema) check(path string) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != "" && !slices.Contains(schemaTypes, s.Type) {
+		return fmt.Errorf("schema %s: unknown type %q", path, s.Type)
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("schema %s: %w", path, err)
+		}
+	}
+	for name, prop := range s.Properties {
+		if err := prop.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	return s.Items.check(path + "[]")
+}
+
+// Validate checks a value against the schema. The error names the path of the first
+// value that does not match, e.g. "$.law_level".
+func (s *Schema) Validate(v any) error {
+	return s.validate("$", v)
+}
+
+func (s *Schema) validate(path string, v any) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != "" && !hasSchemaType(v, s.Type) {
+		return fmt.Errorf("%s: %s is not of type %s", path, describeValue(v), s.Type)
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return fmt.Errorf("%s: %s is not one of %s", path, describeValue(v), describeValue(s.Enum))
+	}
+	switch v := v.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: %v is below the minimum %v", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: %v is above the maximum %v", path, v, *s.Maximum)
+		}
+	case string:
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: %q does not match %q", path, v, s.Pattern)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for name, field := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unknown field %q", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasSchemaType reports whether a decoded JSON value is of a schema type.
+func hasSchemaType(v any, typ string) bool {
+	switch v := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == math.Trunc(v))
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	}
+	return false
+}
+
+// describeValue returns a value as compact JSON for error messages.
+func describeValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(data))
+}
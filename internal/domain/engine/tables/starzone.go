@@ -0,0 +1,212 @@
+package tables
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Columns of a star zone table, in the "columns" of its document.
+const (
+	ColumnTemperature   = "Temperature_K"
+	ColumnMass          = "Mass_SM"
+	ColumnLuminosity    = "Luminosity_SU"
+	ColumnInnerLimit    = "InnerLimit_AU"
+	ColumnHabitableZone = "HabitableZone_AU"
+	ColumnSnowLine      = "SnowLine_AU"
+	ColumnOuterLimit    = "OuterLimit_AU"
+)
+
+// notApplicable marks a zone a star does not have.
+const notApplicable = "N/A"
+
+// StarZoneTable is a "star_zone_table" asset: the physical data and orbital zones of
+// the stars of one luminosity class, keyed by class name ("G2 V").
+type StarZoneTable struct {
+	Name            string              `json:"name"`
+	LuminosityClass string              `json:"luminosity_class"`
+	Columns         []string            `json:"columns"`
+	Stars           map[string]StarZone `json:"-"`
+}
+
+// StarZone is a row of a StarZoneTable. Distances are in AU. As in systemgen, a zone
+// the star does not have ("N/A") is 0, and so is a value the table leaves null.
+type StarZone struct {
+	Class        string
+	Temperature  float64
+	Mass         float64
+	Luminosity   float64
+	InnerLimit   float64
+	HabitableMin float64
+	HabitableMax float64
+	SnowLine     float64
+	OuterLimit   float64
+}
+
+// UnmarshalJSON reads the rows of "data" by the names in "columns", so the columns
+// may come in any order. Every column of the table is required.
+func (szt *StarZoneTable) UnmarshalJSON(data []byte) error {
+	type plain StarZoneTable
+	doc := struct {
+		plain
+		Data map[string][]json.RawMessage `json:"data"`
+	}{}
+	if err := decodeStrict(data, &doc); err != nil {
+		return err
+	}
+	out := StarZoneTable(doc.plain)
+	out.Stars = make(map[string]StarZone, len(doc.Data))
+	for class, cells := range doc.Data {
+		row, err := out.parseRow(class, cells)
+		if err != nil {
+			return err
+		}
+		out.Stars[class] = row
+	}
+	*szt = out
+	return nil
+}
+
+// parseRow reads the cells of a row by the columns of the table.
+func (szt StarZoneTable) parseRow(class string, cells []json.RawMessage) (StarZone, error) {
+	if len(cells) != len(szt.Columns) {
+		return StarZone{}, fmt.Errorf("star %q has %d values for %d columns", class, len(cells), len(szt.Columns))
+	}
+	row := StarZone{Class: class}
+	seen := map[string]bool{}
+	for i, col := range szt.Columns {
+		var err error
+		switch col {
+		case ColumnTemperature:
+			row.Temperature, err = parseZoneNumber(cells[i])
+		case ColumnMass:
+			row.Mass, err = parseZoneNumber(cells[i])
+		case ColumnLuminosity:
+			row.Luminosity, err = parseZoneNumber(cells[i])
+		case ColumnInnerLimit:
+			row.InnerLimit, err = parseZoneNumber(cells[i])
+		case ColumnHabitableZone:
+			row.HabitableMin, row.HabitableMax, err = parseZoneRange(cells[i])
+		case ColumnSnowLine:
+			row.SnowLine, err = parseZoneNumber(cells[i])
+		case ColumnOuterLimit:
+			row.OuterLimit, err = parseZoneNumber(cells[i])
+		default:
+			return StarZone{}, fmt.Errorf("unknown column %q", col)
+		}
+		if err != nil {
+			return StarZone{}, fmt.Errorf("star %q column %q: %w", class, col, err)
+		}
+		seen[col] = true
+	}
+	for _, col := range []string{ColumnTemperature, ColumnMass, ColumnLuminosity, ColumnInnerLimit, ColumnHabitableZone, ColumnSnowLine, ColumnOuterLimit} {
+		if !seen[col] {
+			return StarZone{}, fmt.Errorf("missing column %q", col)
+		}
+	}
+	return row, nil
+}
+
+// parseZoneNumber reads a number, or 0 for "N/A" and null.
+func parseZoneNumber(cell json.RawMessage) (float64, error) {
+	if string(cell) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(cell, &s); err == nil {
+		if s == notApplicable {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	var f float64
+	if err := json.Unmarshal(cell, &f); err != nil {
+		return 0, err
+	}
+	return f, nil
+}
+
+// parseZoneRange reads a range written "lo-hi" (or with an en dash), or 0, 0 for "N/A".
+func parseZoneRange(cell json.RawMessage) (float64, float64, error) {
+	var s string
+	if err := json.Unmarshal(cell, &s); err != nil {
+		return 0, 0, fmt.Errorf("range must be a string: %w", err)
+	}
+	if s == notApplicable {
+		return 0, 0, nil
+	}
+	lo, hi, ok := strings.Cut(strings.ReplaceAll(s, "–", "-"), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not a range", s)
+	}
+	from, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("range %q: %w", s, err)
+	}
+	to, err := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("range %q: %w", s, err)
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("range %q is reversed", s)
+	}
+	return from, to, nil
+}
+
+// AssetName returns the name of the table.
+func (szt StarZoneTable) AssetName() string {
+	return szt.Name
+}
+
+// Validate checks that the table has a class and stars with their limits in order.
+func (szt StarZoneTable) Validate() error {
+	if szt.Name == "" {
+		return errors.New("star zone table name cannot be empty")
+	}
+	if szt.LuminosityClass == "" {
+		return fmt.Errorf("star zone table %q has no luminosity class", szt.Name)
+	}
+	if len(szt.Stars) == 0 {
+		return fmt.Errorf("star zone table %q has no stars", szt.Name)
+	}
+	for class, star := range szt.Stars {
+		if star.InnerLimit > star.OuterLimit {
+			return fmt.Errorf("star zone table %q: star %q has inner limit beyond outer limit", szt.Name, class)
+		}
+	}
+	return nil
+}
+
+// Star returns the row of a star class.
+func (szt StarZoneTable) Star(class string) (StarZone, bool) {
+	star, ok := szt.Stars[class]
+	return star, ok
+}
+
+// Classes returns the star classes of the table, hottest first.
+func (szt StarZoneTable) Classes() []string {
+	classes := slices.Sorted(maps.Keys(szt.Stars))
+	slices.SortStableFunc(classes, func(a, b string) int {
+		ta, tb := szt.Stars[a].Temperature, szt.Stars[b].Temperature
+		switch {
+		case ta > tb:
+			return -1
+		case ta < tb:
+			return 1
+		}
+		return 0
+	})
+	return classes
+}
+
+func decodeStarZoneTable(data []byte) (Asset, error) {
+	szt := StarZoneTable{}
+	if err := json.Unmarshal(data, &szt); err != nil {
+		return nil, err
+	}
+	return szt, nil
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/Galdoba/cepheus/internal/domain/engine/dice"
 )
 
 var (
@@ -19,6 +22,14 @@ type GameTable struct {
 	Expression string            `json:"expression"`
 	Data       map[string]string `json:"data"`
 	D66        bool              `json:"d_66"`
+	// Rows holds the fields of the structured entries, by key; their text is in
+	// Data. In JSON a structured entry is an object in "data".
+	Rows map[string]map[string]any `json:"-"`
+	// Label names the field that is the text of a structured entry.
+	Label string `json:"label,omitempty"`
+	// Schema, when set, checks every entry: the fields of a structured entry or the
+	// string of a plain one.
+	Schema *Schema `json:"schema,omitempty"`
 }
 
 func New(name, expression string, data map[string]string) GameTable {
@@ -42,7 +53,7 @@ func (t GameTable) Validate() error {
 	indexes := make([]int, 0, len(t.Data))
 	indexMet := make(map[int]int)
 	for k := range t.Data {
-		idx, err := stringToIndexes(k)
+		idx, err := t.indexes(k)
 		if err != nil {
 			return fmt.Errorf("table %q has invalid index %q: %w", t.Name, k, err)
 		}
@@ -57,9 +68,13 @@ func (t GameTable) Validate() error {
 	sort.Ints(indexes)
 	min, max := indexes[0], indexes[len(indexes)-1]
 	expectedCount := max - min + 1
-	if !t.D66 && len(indexes) != expectedCount {
+	totals, exact := t.totals()
+	if !t.D66 && !isDigitExpression(t.Expression) && !isConcatExpression(t.Expression) && !(exact && gapped(totals)) && len(indexes) != expectedCount {
 		return fmt.Errorf("table %q has holes in index range [%d, %d]", t.Name, min, max)
 	}
+	if err := t.validateCoverage(indexMet, totals); err != nil {
+		return err
+	}
 	for _, idx := range indexes {
 		if idx < -1000 || idx > 1000 {
 			return fmt.Errorf("table %q has index %d out of bounds [-1000, 1000]", t.Name, idx)
@@ -73,9 +88,111 @@ func (t GameTable) Validate() error {
 			return fmt.Errorf("table %q has empty value", t.Name)
 		}
 	}
+	if err := t.validateRows(); err != nil {
+		return err
+	}
+	return t.validateTemplates()
+}
+
+// validateCoverage checks that every total of the expression, as given by totals, has
+// an entry as far as it lies within the index bounds. Digit and concat tables have
+// natural holes and are not checked.
+func (t GameTable) validateCoverage(covered map[int]int, totals []int) error {
+	if t.D66 || isDigitExpression(t.Expression) || isConcatExpression(t.Expression) {
+		return nil
+	}
+	for _, v := range totals {
+		if v < DefaultLowerBound || v > DefaultUpperBound {
+			continue
+		}
+		if covered[v] == 0 {
+			return fmt.Errorf("table %q has no entry for %d, %q rolls %d to %d", t.Name, v, t.Expression, totals[0], totals[len(totals)-1])
+		}
+	}
 	return nil
 }
 
+// totals returns the totals the expression can roll without DMs, ascending. They are
+// exact when the dice package can compute the distribution of the expression, so
+// expressions with gaps in their range ("1d6*10", "1d6!") list only what they reach.
+// Otherwise they are only the lowest and highest total.
+func (t GameTable) totals() ([]int, bool) {
+	if t.D66 || isDigitExpression(t.Expression) || isConcatExpression(t.Expression) {
+		return nil, false
+	}
+	if pd, err := dice.Distribution(t.Expression); err == nil {
+		return pd.Outcomes(), true
+	}
+	ex, err := inspect(t.Expression)
+	if err != nil {
+		return nil, false // reported by validateExpression
+	}
+	return []int{ex.Min, ex.Max}, false
+}
+
+// gapped reports whether ascending totals skip a value.
+func gapped(totals []int) bool {
+	return len(totals) > 0 && totals[len(totals)-1]-totals[0]+1 != len(totals)
+}
+
+// indexes parses a key of the table. In a percentile table "00" is 100, so
+// "96-00" covers 96 to 100.
+func (t GameTable) indexes(key string) ([]int, error) {
+	if isPercentileExpression(t.Expression) {
+		key = doubleZeroPattern.ReplaceAllString(key, "${1}100")
+	}
+	return stringToIndexes(key)
+}
+
+// keyAt returns the key that covers index, or "" when none does.
+func (t GameTable) keyAt(index int) string {
+	for key := range t.Data {
+		candidates, _ := t.indexes(key) //skip error because it supposed to be validated by now
+		if slices.Contains(candidates, index) {
+			return key
+		}
+	}
+	return ""
+}
+
+// rangesOf returns the index ranges of the keys holding value, as [lo, hi] pairs in
+// ascending order. A key listing separate indexes ("2,4") gives one range each.
+func (t GameTable) rangesOf(value string) [][2]int {
+	var ranges [][2]int
+	for key, v := range t.Data {
+		if v != value {
+			continue
+		}
+		indexes, err := t.indexes(key)
+		if err != nil {
+			continue
+		}
+		slices.Sort(indexes)
+		for i, idx := range indexes {
+			if i > 0 && idx == indexes[i-1]+1 {
+				ranges[len(ranges)-1][1] = idx
+				continue
+			}
+			ranges = append(ranges, [2]int{idx, idx})
+		}
+	}
+	slices.SortFunc(ranges, func(a, b [2]int) int { return a[0] - b[0] })
+	return ranges
+}
+
+// keyFor returns the key for a rolled digit code: the key spelled exactly like the
+// code, or else the key whose range covers it as a number ("11-16" holds "13").
+func (t GameTable) keyFor(code string) string {
+	if _, ok := t.Data[code]; ok {
+		return code
+	}
+	index, err := strconv.Atoi(code)
+	if err != nil {
+		return ""
+	}
+	return t.keyAt(index)
+}
+
 // type TableCollection struct {
 // 	tables map[string]GameTable
 // }
@@ -142,6 +259,13 @@ func indexesToString(indexes ...int) (string, error) {
 	return strings.Join(groups, ", "), nil
 }
 
+var (
+	rangePattern  = regexp.MustCompile(`^(-?\d+)\s*-\s*(-?\d+)$`)
+	plusPattern   = regexp.MustCompile(`^(-?\d+)\+$`)
+	minusPattern  = regexp.MustCompile(`^(-?\d+)\-$`)
+	numberPattern = regexp.MustCompile(`^(-?\d+)$`)
+)
+
 func stringToIndexes(s string) ([]int, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -151,11 +275,6 @@ func stringToIndexes(s string) ([]int, error) {
 	parts := strings.Split(s, ",")
 	result := make(map[int]bool)
 
-	rangePattern := regexp.MustCompile(`^(-?\d+)\s*-\s*(-?\d+)$`)
-	plusPattern := regexp.MustCompile(`^(-?\d+)\+$`)
-	minusPattern := regexp.MustCompile(`^(-?\d+)\-$`)
-	numberPattern := regexp.MustCompile(`^(-?\d+)$`)
-
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
@@ -222,36 +341,59 @@ func mustParseInt(s string) int {
 
 //expresion validation
 
-var dicePattern = regexp.MustCompile(`^(\d*)d(\d+)([+-]\d+)?$`)
+var (
+	digitPattern      = regexp.MustCompile(`^D[02-9]{2,}$`)
+	doubleZeroPattern = regexp.MustCompile(`(^|[^\d])00\b`)
+)
 
-func validateExpression(expr string) error {
-	if expr == "d66" || expr == "D66" {
-		return nil
-	}
-	matches := dicePattern.FindStringSubmatch(expr)
-	if matches == nil {
-		return fmt.Errorf("invalid format: expected 'XdY', 'XdY+Z' or 'XdY-Z'")
+// isDigitExpression reports whether expression reads one die per digit ("D666",
+// "D36", "D00"), as rolled by DigitRoller. Only an uppercase D reads digits: "d20"
+// is an ordinary d20.
+func isDigitExpression(expr string) bool {
+	return digitPattern.MatchString(expr)
+}
+
+// isPercentileExpression reports whether expression rolls a single d100, where the
+// key "00" stands for 100.
+func isPercentileExpression(expr string) bool {
+	ex, err := dice.Inspect(expr)
+	if err != nil || len(ex.Groups) != 1 {
+		return false
 	}
+	g := ex.Groups[0]
+	return g.Count == 1 && g.Faces == 100 && g.Die == "" && ex.Min == 1 && ex.Max == 100
+}
 
-	xStr, yStr, modStr := matches[1], matches[2], matches[3]
+// isConcatExpression reports whether expression reads dice as digits ("3D6"),
+// such tables have natural holes in their index range.
+func isConcatExpression(expr string) bool {
+	ex, err := dice.Inspect(expr)
+	return err == nil && slices.ContainsFunc(ex.Groups, func(g dice.DiceGroup) bool {
+		return g.Type == dice.DiceConcat
+	})
+}
 
-	y, err := strconv.Atoi(yStr)
-	if err != nil || y <= 0 {
-		return fmt.Errorf("number of faces (Y) must be a positive integer, got %q", yStr)
+// validateExpression checks the expression with the dice package, which also knows
+// its range. Digit expressions are rolled by DigitRoller and checked by the dice
+// package as digit specs.
+func validateExpression(expr string) error {
+	if expr == "d66" || expr == "D66" || isDigitExpression(expr) {
+		return nil
 	}
+	_, err := inspect(expr)
+	return err
+}
 
-	if xStr != "" {
-		x, err := strconv.Atoi(xStr)
-		if err != nil || x <= 0 {
-			return fmt.Errorf("number of dice (X) must be a positive integer, got %q", xStr)
-		}
+// inspect parses an expression that must roll at least one die.
+func inspect(expr string) (dice.Expression, error) {
+	ex, err := dice.Inspect(expr)
+	if err != nil {
+		return dice.Expression{}, err
 	}
-
-	if modStr != "" {
-		if _, err := strconv.Atoi(modStr); err != nil {
-			return fmt.Errorf("modifier (N) must be an integer, got %q", modStr)
+	for _, g := range ex.Groups {
+		if g.Count < 1 {
+			return dice.Expression{}, fmt.Errorf("%s rolls no dice", g.Canonical)
 		}
 	}
-
-	return nil
+	return ex, nil
 }
@@ -1,9 +1,17 @@
 package tables
 
 import (
+	"encoding/json"
 	"errors"
+	"math"
+	"reflect"
 	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/Galdoba/cepheus/internal/domain/engine/dice"
 )
 
 // ---------------------------------------------------------------------
@@ -116,6 +124,66 @@ func TestGameTableValidate(t *testing.T) {
 			table:   New("test", "d6", map[string]string{"1": "a", "2": "b", "3": "c", "3 - 4": "d"}),
 			wantErr: true,
 		},
+		{
+			name:    "entries short of the expression range",
+			table:   New("test", "2d6", map[string]string{"2-6": "a", "7-11": "b"}),
+			wantErr: true,
+		},
+		{
+			name:    "entries beyond the expression range for DMs",
+			table:   New("test", "d6", map[string]string{"0": "a", "1-6": "b", "7": "c"}),
+			wantErr: false,
+		},
+		{
+			name:    "drop lowest covers 3 to 18",
+			table:   New("test", "4d6:dl1", map[string]string{"3-9": "a", "10-18": "b"}),
+			wantErr: false,
+		},
+		{
+			name:    "two dice groups",
+			table:   New("test", "2d6+1d3", map[string]string{"3-8": "a", "9-14": "b"}),
+			wantErr: true,
+		},
+		{
+			name:    "two dice groups covered",
+			table:   New("test", "2d6+1d3", map[string]string{"3-8": "a", "9-15": "b"}),
+			wantErr: false,
+		},
+		{
+			name:    "exploding die covered by an open-ended key",
+			table:   New("test", "1d6!", map[string]string{"1-5": "a", "6+": "b"}),
+			wantErr: false,
+		},
+		{
+			name:    "exploding die needs no entry for the 6 it cannot total",
+			table:   New("test", "1d6!", map[string]string{"1-5": "a", "7+": "b"}),
+			wantErr: false,
+		},
+		{
+			name:    "exploding die needs entries for the totals it reaches",
+			table:   New("test", "1d6!", map[string]string{"1-5": "a", "7-11": "b"}),
+			wantErr: true,
+		},
+		{
+			name:    "multiplied die covers only its multiples",
+			table:   New("test", "1d6*10", map[string]string{"10": "a", "20": "b", "30": "c", "40": "d", "50": "e", "60": "f"}),
+			wantErr: false,
+		},
+		{
+			name:    "multiplied dice short of a reachable total",
+			table:   New("test", "2d6*10", map[string]string{"20-60": "a", "70-110": "b"}),
+			wantErr: true,
+		},
+		{
+			name:    "hole at unreachable totals",
+			table:   New("test", "2d6*10", map[string]string{"20-60": "a", "70-120": "b"}),
+			wantErr: false,
+		},
+		{
+			name:    "hole at a reachable total",
+			table:   New("test", "2d6*10", map[string]string{"20-60": "a", "70-100": "b", "120": "c"}),
+			wantErr: true,
+		},
 		{
 			name:    "valid table with modifier",
 			table:   New("test", "2d6+1", map[string]string{"3": "a", "4": "b", "5": "c", "6": "d", "7": "e", "8": "f", "9": "g", "10": "h", "11": "i", "12": "j", "13": "k"}),
@@ -148,6 +216,17 @@ func TestValidateExpression(t *testing.T) {
 		{"d20-1", false},
 		{"D66", false},
 		{"d66", false},
+		{"3D6", false},
+		{"2D10", false},
+		{"3DD6", false},
+		{"3DD6+1", false},
+		{"4d6:dl1", false},
+		{"2d6+1d3", false},
+		{"3d6:kh2", false},
+		{"1d6!", false},
+		{"2d6:bogus", true},
+		{"DD6", true},
+		{"1DD6", true},
 		{"", true},
 		{"d", true},
 		{"d0", true},
@@ -281,7 +360,7 @@ func TestNewCollection(t *testing.T) {
 	})
 
 	t.Run("empty collection name", func(t *testing.T) {
-		table := New("t", "d6", map[string]string{"1": "a", "2": "b"})
+		table := New("t", "d6", map[string]string{"1-3": "a", "4-6": "b"})
 		_, err := NewCollection("", table)
 		if err == nil {
 			t.Fatal("expected error for empty collection name, got nil")
@@ -337,7 +416,7 @@ func TestCollectionRoll(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if result != "seven" {
+		if result.Text != "seven" {
 			t.Errorf("got %q, want %q", result, "seven")
 		}
 		// Check that rollSequence and results were recorded.
@@ -358,11 +437,33 @@ func TestCollectionRoll(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if result != "one two" {
+		if result.Text != "one two" {
 			t.Errorf("got %q, want %q", result, "one two")
 		}
 	})
 
+	t.Run("roll concat table", func(t *testing.T) {
+		concatTable := New("concat", "3D6", map[string]string{
+			"111":       "lowest",
+			"112 - 665": "middle",
+			"666":       "highest",
+		})
+		concatColl, err := NewCollection("concat", concatTable)
+		if err != nil {
+			t.Fatalf("concat table with holes should be valid: %v", err)
+		}
+		roller := &mockRoller{
+			rollResults: map[string]int{"3D6": 666},
+		}
+		result, err := concatColl.Roll(roller, "concat")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Text != "highest" {
+			t.Errorf("got %q, want %q", result, "highest")
+		}
+	})
+
 	t.Run("missing table", func(t *testing.T) {
 		roller := &mockRoller{}
 		_, err := coll.Roll(roller, "nonexistent")
@@ -441,7 +542,7 @@ func TestCollectionRollCascade(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if result != "end" {
+		if result.Text != "end" {
 			t.Errorf("got %q, want %q", result, "end")
 		}
 		// Should have rolled only on A.
@@ -462,7 +563,7 @@ func TestCollectionRollCascade(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if result != "final" {
+		if result.Text != "final" {
 			t.Errorf("got %q, want %q", result, "final")
 		}
 		expectedSeq := []string{"A", "B", "C"}
@@ -486,7 +587,7 @@ func TestCollectionRollCascade(t *testing.T) {
 
 	t.Run("cascade max depth exceeded", func(t *testing.T) {
 		// Create a loop: table Loop points to itself.
-		loopTable := New("Loop", "d6", map[string]string{"1": "Loop", "2": "Loop"})
+		loopTable := New("Loop", "d6", map[string]string{"1-3": "Loop", "4-6": "Loop"})
 		coll2, _ := NewCollection("loopcoll", loopTable)
 		roller := &mockRoller{
 			rollResults: map[string]int{"d6": 1},
@@ -498,9 +599,222 @@ func TestCollectionRollCascade(t *testing.T) {
 	})
 }
 
+func TestCollectionRollCascadeSimulated(t *testing.T) {
+	// A leads to "final" on 2, or on 1 followed by B's 1: P = 1/6 + 1/36.
+	coll, err := NewCollection("cascade",
+		New("A", "d6", map[string]string{"1": "B", "2": "C", "3": "end", "4": "end", "5": "end", "6": "end"}),
+		New("B", "d6", map[string]string{"1": "C", "2": "end", "3": "end", "4": "end", "5": "end", "6": "end"}),
+		New("C", "d6", map[string]string{"1": "final", "2": "final", "3": "final", "4": "final", "5": "final", "6": "final"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	// The collection records its rolls, so the trials share one worker.
+	stats, err := dice.SimulateFunc(20000, dice.SimulateOptions{Seed: "cascade"}, func(m *dice.Manager) (int, error) {
+		coll.Reset()
+		result, err := coll.RollCascade(m, "A")
+		if result.Text == "final" {
+			return 1, err
+		}
+		return 0, err
+	})
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if want := 7.0 / 36; math.Abs(stats.Mean()-want) > 0.01 {
+		t.Errorf("P(final) = %.3f, want about %.3f", stats.Mean(), want)
+	}
+}
+
+func TestCollectionForce(t *testing.T) {
+	coll, err := NewCollection("force",
+		New("Encounter", "2d6", map[string]string{"2": "Dragon", "3-6": "Bandits", "7-9": "Nothing", "10-11": "Merchant", "12": "Dragon"}),
+		New("Loot", "D66", map[string]string{"11-36": "Coins", "41-66": "Gems"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	m, err := dice.New("force table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 20 {
+		got, err := coll.Force(m, "Encounter", "Dragon", 1)
+		if err != nil {
+			t.Fatalf("Force failed: %v", err)
+		}
+		if got.Text != "Dragon" {
+			t.Fatalf("Force = %q, want Dragon", got)
+		}
+		// With +1 the "2" key is out of reach, so the dice land on 11.
+		raw := m.Result().Raw()
+		if len(raw) != 2 || raw[0]+raw[1] != 11 {
+			t.Errorf("forced dice %v, want a total of 11", raw)
+		}
+	}
+	if got := coll.rollSequence[len(coll.rollSequence)-1]; got != "Encounter" {
+		t.Errorf("roll sequence ends with %q, want Encounter", got)
+	}
+
+	if _, err := coll.Force(m, "Encounter", "Unicorn"); err == nil {
+		t.Errorf("forcing a missing entry should fail")
+	}
+	if _, err := coll.Force(m, "Loot", "Gems"); err == nil {
+		t.Errorf("forcing a D66 table should fail")
+	}
+	if _, err := coll.Force(m, "Encounter", "Bandits", 20); !errors.Is(err, dice.ErrUnreachable) {
+		t.Errorf("Force out of reach error = %v, want ErrUnreachable", err)
+	}
+}
+
+func TestCollectionExpressions(t *testing.T) {
+	coll, err := NewCollection("exprs",
+		New("A", "2d6", map[string]string{"2-6": "x", "7-12": "y"}),
+		New("B", "1d6", map[string]string{"1-3": "x", "4-6": "y"}),
+		New("C", "2d6", map[string]string{"2-7": "x", "8-12": "y"}),
+		New("D", "D66", map[string]string{"11-36": "x", "41-66": "y"}),
+		New("E", "D666", map[string]string{"111-366": "x", "411-666": "y"}),
+		New("F", "D36", map[string]string{"11-26": "x", "31-36": "y"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	got := coll.Expressions()
+	if want := []string{"1d6", "2d6"}; !slices.Equal(got, want) {
+		t.Errorf("Expressions() = %v, want %v", got, want)
+	}
+	cache := dice.NewCache(8)
+	if err := cache.Warm(got...); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("warmed cache holds %d entries, want 2", cache.Len())
+	}
+}
+
+func TestPercentileTable(t *testing.T) {
+	table := New("Object", "1d100", map[string]string{
+		"01 - 80": "Star",
+		"81 - 97": "Planet",
+		"98":      "Neutron Star",
+		"99":      "Nebula",
+		"00":      "Black Hole",
+	})
+	if err := table.Validate(); err != nil {
+		t.Fatalf("percentile table should validate: %v", err)
+	}
+	coll, err := NewCollection("percentile", table,
+		New("Range", "d100", map[string]string{"1-95": "common", "96-00": "rare"}))
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	for _, tt := range []struct {
+		table string
+		roll  int
+		want  string
+	}{
+		{"Object", 100, "Black Hole"},
+		{"Object", 1, "Star"},
+		{"Object", 99, "Nebula"},
+		{"Range", 100, "rare"},
+		{"Range", 96, "rare"},
+	} {
+		roller := &mockRoller{rollResults: map[string]int{coll.Tables[tt.table].Expression: tt.roll}}
+		got, err := coll.Roll(roller, tt.table)
+		if err != nil || got.Text != tt.want {
+			t.Errorf("roll %d on %q = %q, %v; want %q", tt.roll, tt.table, got, err, tt.want)
+		}
+	}
+	// "00" keeps meaning 0 outside percentile tables.
+	if idx, _ := New("Plain", "2d6", nil).indexes("00"); !slices.Equal(idx, []int{0}) {
+		t.Errorf("indexes(00) of a 2d6 table = %v, want [0]", idx)
+	}
+}
+
+// digitRoller is a mockRoller that can also roll digit dice.
+type digitRoller struct {
+	mockRoller
+	codes map[string]string // spec -> digits
+}
+
+func (d *digitRoller) Digits(spec string, mods ...int) (string, error) {
+	return d.codes[spec], nil
+}
+
+func TestDigitTable(t *testing.T) {
+	coll, err := NewCollection("digits",
+		New("Encounter", "D666", map[string]string{
+			"111-366": "nothing",
+			"411-566": "patrol",
+			"611-666": "pirates",
+		}),
+		New("Ranged", "D66", map[string]string{"11-36": "low", "41-66": "high"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	roller := &digitRoller{mockRoller: mockRoller{d66Result: "24"}, codes: map[string]string{"D666": "512"}}
+	if got, err := coll.Roll(roller, "Encounter"); err != nil || got.Text != "patrol" {
+		t.Errorf("D666 roll = %q, %v; want patrol", got, err)
+	}
+	if got, err := coll.Roll(roller, "Ranged"); err != nil || got.Text != "low" {
+		t.Errorf("D66 roll on a ranged key = %q, %v; want low", got, err)
+	}
+	if _, err := coll.Roll(&mockRoller{}, "Encounter"); err == nil {
+		t.Errorf("a roller without Digits should fail on a D666 table")
+	}
+
+	m, err := dice.New("digit table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 50 {
+		if _, err := coll.Roll(m, "Encounter", 0, 0, 0); err != nil {
+			t.Fatalf("D666 roll with a dice.Manager failed: %v", err)
+		}
+	}
+}
+
+func TestLowercaseDiceAreNotDigits(t *testing.T) {
+	for _, expr := range []string{"d20", "d30", "d22", "d00"} {
+		if isDigitExpression(expr) {
+			t.Errorf("isDigitExpression(%q) = true, want an ordinary die", expr)
+		}
+	}
+	if err := New("t", "d20", map[string]string{"1": "a", "2": "b"}).Validate(); err == nil {
+		t.Error("a d20 table with entries for 1 and 2 only passed Validate")
+	}
+	data := map[string]string{}
+	for i := 1; i <= 20; i++ {
+		data[strconv.Itoa(i)] = strconv.Itoa(i)
+	}
+	coll, err := NewCollection("d20", New("d20", "d20", data))
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	m, err := dice.New("d20 table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for range 2000 {
+		row, err := coll.Roll(m, "d20")
+		if err != nil {
+			t.Fatalf("Roll on a d20 table failed: %v", err)
+		}
+		seen[row.Text] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("d20 table rolled %d distinct results in 2000 rolls, want 20", len(seen))
+	}
+}
+
 func TestCollectionReset(t *testing.T) {
-	table := New("t", "d6", map[string]string{"1": "a", "2": "b"})
-	coll, _ := NewCollection("test", table)
+	table := New("t", "d6", map[string]string{"1-3": "a", "4-6": "b"})
+	coll, err := NewCollection("test", table)
+	if err != nil {
+		t.Fatal(err)
+	}
 	roller := &mockRoller{
 		rollResults: map[string]int{"d6": 1},
 	}
@@ -517,7 +831,7 @@ func TestCollectionReset(t *testing.T) {
 }
 
 func TestCollectionValidate(t *testing.T) {
-	validTable := New("t", "d6", map[string]string{"1": "a", "2": "b"})
+	validTable := New("t", "d6", map[string]string{"1-3": "a", "4-6": "b"})
 
 	t.Run("valid collection", func(t *testing.T) {
 		coll, _ := NewCollection("good", validTable)
@@ -547,4 +861,841 @@ func TestCollectionValidate(t *testing.T) {
 			t.Error("expected error for invalid table, got nil")
 		}
 	})
+
+	t.Run("d6 table with entries for 1 and 2 only", func(t *testing.T) {
+		// The fixture the collection tests used before coverage was checked: four of
+		// the six faces land on no entry.
+		partial := New("t", "d6", map[string]string{"1": "a", "2": "b"})
+		coll := &Collection{Name: "partial", Tables: map[string]GameTable{"t": partial}}
+		if _, err := coll.Roll(&mockRoller{rollResults: map[string]int{"d6": 3}}, "t"); err == nil || !strings.Contains(err.Error(), "result is empty") {
+			t.Errorf("Roll on 3 error = %v, want an empty result", err)
+		}
+		if err := coll.Validate(); err == nil || !strings.Contains(err.Error(), "no entry for 3") {
+			t.Errorf("Validate error = %v, want no entry for 3", err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------
+// Assets and catalogue
+// ---------------------------------------------------------------------
+
+func TestDecodeAsset(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		wantType string
+		wantErr  string
+	}{
+		{"untyped table", `{"name": "T", "expression": "1d6", "data": {"1-3": "a", "4-6": "b"}}`, AssetTable, ""},
+		{"ring details", `{"name": "R", "type": "ring_details_extended", "base_roll": "d10", "data": {"1-5": "a", "6-10": "b"}}`, AssetRingDetails, ""},
+		{"unknown type", `{"name": "X", "type": "spell_table"}`, "", `unknown asset type "spell_table"`},
+		{"unknown field", `{"name": "F", "type": "formula", "formula": "1d6", "formule": "2d6"}`, "", `unknown field "formule"`},
+		{"invalid table", `{"name": "T", "expression": "1d6", "data": {"1-3": "a"}}`, "", "invalid table"},
+		{"formula without roll", `{"name": "F", "type": "formula"}`, "", "either formula or formulas"},
+		{"modifier defined twice", `{"name": "M", "type": "modifier_table", "size_modifiers": {"a": {"condition": "x", "modifier": 1}}, "atmosphere_modifiers": {"a": {"condition": "y", "modifier": 2}}}`, "", `modifier "a" is defined twice`},
+		{"overlapping thresholds", `{"name": "T", "type": "threshold_table", "base_roll": "2d6", "thresholds": [{"max": 7, "result": "a"}, {"min": 7, "result": "b"}]}`, "", "overlaps"},
+		{"star zone missing column", `{"name": "S", "type": "star_zone_table", "luminosity_class": "V", "columns": ["Temperature_K"], "data": {"G2 V": [5780]}}`, "", `missing column "Mass_SM"`},
+		{"reversed habitable zone", `{"name": "S", "type": "star_zone_table", "luminosity_class": "V", "columns": ["Temperature_K", "Mass_SM", "Luminosity_SU", "InnerLimit_AU", "HabitableZone_AU", "SnowLine_AU", "OuterLimit_AU"], "data": {"F1 V": [7260, 1.6, 6.56, 0.32, "2.43-1.64", 12.81, 64]}}`, "", "reversed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, _, err := DecodeAsset([]byte(tt.doc))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeAsset error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeAsset failed: %v", err)
+			}
+			if typ != tt.wantType {
+				t.Errorf("type = %q, want %q", typ, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestRegisterAssetType(t *testing.T) {
+	if err := RegisterAssetType(AssetFormula, decodeFormula); err == nil {
+		t.Error("registering a built-in type twice should fail")
+	}
+	if err := RegisterAssetType("test_note", nil); err == nil {
+		t.Error("registering a type without a decoder should fail")
+	}
+	decode := func(data []byte) (Asset, error) {
+		ft := FormulaTable{}
+		return ft, decodeStrict(data, &ft)
+	}
+	if err := RegisterAssetType("test_note", decode); err != nil {
+		t.Fatalf("RegisterAssetType failed: %v", err)
+	}
+	t.Cleanup(func() {
+		assetTypes.mu.Lock()
+		delete(assetTypes.decoders, "test_note")
+		assetTypes.mu.Unlock()
+	})
+	if !slices.Contains(AssetTypes(), "test_note") {
+		t.Errorf("AssetTypes() = %v, want test_note", AssetTypes())
+	}
+	typ, asset, err := DecodeAsset([]byte(`{"name": "N", "type": "test_note", "data": {"a": "1d6"}}`))
+	if err != nil {
+		t.Fatalf("DecodeAsset failed: %v", err)
+	}
+	if typ != "test_note" || asset.AssetName() != "N" {
+		t.Errorf("DecodeAsset = %q %q, want test_note N", typ, asset.AssetName())
+	}
+}
+
+func TestLoadCatalogue(t *testing.T) {
+	cat, err := LoadCatalogueDir("../../../../assets")
+	if err != nil {
+		t.Fatalf("LoadCatalogueDir failed: %v", err)
+	}
+	for _, typ := range AssetTypes() {
+		if len(cat.List(typ)) == 0 {
+			t.Errorf("no assets of type %q", typ)
+		}
+	}
+
+	sun, ok := cat.StarZone("G2 V")
+	if !ok {
+		t.Fatal("star G2 V not found")
+	}
+	if sun.Temperature != 5780 || sun.HabitableMin != 0.95 || sun.HabitableMax != 1.3 || sun.OuterLimit != 40 {
+		t.Errorf("G2 V = %+v", sun)
+	}
+	zones, err := cat.StarZoneTable("generic_starzone_V")
+	if err != nil {
+		t.Fatalf("StarZoneTable failed: %v", err)
+	}
+	if classes := zones.Classes(); classes[0] != "O0 V" {
+		t.Errorf("hottest class = %q, want O0 V", classes[0])
+	}
+
+	gov, err := cat.GovernmentTable("generic_gov_terran_hz")
+	if err != nil {
+		t.Fatalf("GovernmentTable failed: %v", err)
+	}
+	if row, ok := gov.Lookup(5); !ok || row.Government != "Captive research" || row.LawLevel != "2d6-1" {
+		t.Errorf("Lookup(5) = %+v, %v", row, ok)
+	}
+
+	mods, err := cat.ModifierTable("generic_superterran_hydro_modifiers")
+	if err != nil {
+		t.Fatalf("ModifierTable failed: %v", err)
+	}
+	if groups := mods.Groups(); !slices.Equal(groups, []string{"atmosphere", "size"}) {
+		t.Errorf("Groups() = %v, want [atmosphere size]", groups)
+	}
+
+	core, err := cat.ThresholdTable("generic_core_type")
+	if err != nil {
+		t.Fatalf("ThresholdTable failed: %v", err)
+	}
+	for total, want := range map[int]string{2: "Molten", 7: "Rocky", 21: "Icy"} {
+		if got, _ := core.Lookup(total); got != want {
+			t.Errorf("core Lookup(%d) = %q, want %q", total, got, want)
+		}
+	}
+
+	if _, err := cat.Formula("generic_core_type"); err == nil || !strings.Contains(err.Error(), "is a threshold_table") {
+		t.Errorf("Formula on a threshold table error = %v", err)
+	}
+	if _, err := cat.Table("no_such_asset"); err == nil {
+		t.Error("missing asset should fail")
+	}
+
+	coll, err := cat.Collection("assets")
+	if err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	got, err := coll.Roll(&mockRoller{d66Result: "13"}, "System Quirks")
+	if err != nil || got.Text != "Water-core planet" {
+		t.Errorf("Roll(System Quirks) = %q, %v, want Water-core planet", got, err)
+	}
+}
+
+func TestLoadCatalogueErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"good.json":      {Data: []byte(`{"name": "G", "expression": "1d6", "data": {"1-6": "x", "7": "y"}}`)},
+		"bad/type.json":  {Data: []byte(`{"name": "B", "type": "bogus"}`)},
+		"bad/short.json": {Data: []byte(`{"name": "S", "expression": "1d6", "data": {"1": "x"}}`)},
+		"notes.txt":      {Data: []byte("not an asset")},
+	}
+	_, err := LoadLayers(Layer{Name: "test", FS: fsys})
+	if err == nil {
+		t.Fatal("LoadCatalogue should fail")
+	}
+	for _, want := range []string{`"test:bad/type.json"`, `"test:bad/short.json"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not name %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "good.json") || strings.Contains(err.Error(), "notes.txt") {
+		t.Errorf("error %v names a valid or skipped file", err)
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	builtin, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	dir, err := LoadCatalogueDir("../../../../assets")
+	if err != nil {
+		t.Fatalf("LoadCatalogueDir failed: %v", err)
+	}
+	if builtin.Len() != dir.Len() {
+		t.Errorf("embedded bundle has %d assets, the directory %d", builtin.Len(), dir.Len())
+	}
+	info, ok := builtin.Info("system_quirks")
+	if !ok {
+		t.Fatal("system_quirks not found")
+	}
+	if info.Layer != BuiltinLayer || info.Origin() != "builtin:system_quirks.json" {
+		t.Errorf("system_quirks origin = %q", info.Origin())
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	campaign := fstest.MapFS{
+		// Replaces the built-in table under another file name.
+		"quirks.json": {Data: []byte(`{"name": "System Quirks", "expression": "1d6", "data": {"1-3": "None", "4-6": "Rogue comet"}}`)},
+		// Patches two rows of a government table and leaves the others.
+		"gov.json": {Data: []byte(`{"name": "Government (Terran, Habitable Zone)", "patch": true, "data": {"3": {"government": "Megacorporation"}, "10-12": {"code": "7", "government": "Balkanised", "law_level": "2d6"}}}`)},
+		"new.json": {Data: []byte(`{"name": "Campaign Rumours", "expression": "1d6", "data": {"1-5": "Nothing", "6": "Pirates"}}`)},
+	}
+	house := fstest.MapFS{
+		"core.json": {Data: []byte(`{"name": "Planet Core Type", "patch": true, "thresholds": [{"max": 8, "result": "Molten"}, {"min": 9, "result": "Rocky"}], "modifiers": null}`)},
+		"gov.json":  {Data: []byte(`{"name": "Government (Terran, Habitable Zone)", "patch": true, "base_roll": "2d6"}`)},
+	}
+	cat, err := LoadDefault(Layer{Name: "campaign", FS: campaign}, Layer{Name: "house", FS: house})
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+
+	quirks, err := cat.Table("system_quirks")
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if quirks.Expression != "1d6" || len(quirks.Data) != 2 {
+		t.Errorf("replaced table = %+v", quirks)
+	}
+	info, _ := cat.Named("System Quirks")
+	if want := "campaign:quirks.json (replaces builtin:system_quirks.json)"; info.Origin() != want {
+		t.Errorf("Origin() = %q, want %q", info.Origin(), want)
+	}
+
+	gov, err := cat.GovernmentTable("generic_gov_terran_hz")
+	if err != nil {
+		t.Fatalf("GovernmentTable failed: %v", err)
+	}
+	if row, _ := gov.Lookup(3); row.Government != "Megacorporation" || row.Code != "1" || row.LawLevel != "2d6-1" {
+		t.Errorf("patched row 3 = %+v", row)
+	}
+	if row, _ := gov.Lookup(11); row.Code != "7" {
+		t.Errorf("patched row 10-12 = %+v", row)
+	}
+	if row, _ := gov.Lookup(7); row.Government != "Captive colony" {
+		t.Errorf("unpatched row 6-9 = %+v", row)
+	}
+	info, _ = cat.Info("generic_gov_terran_hz")
+	if want := "builtin:generic_gov_terran_hz.json (patched by campaign:gov.json, house:gov.json)"; info.Origin() != want {
+		t.Errorf("Origin() = %q, want %q", info.Origin(), want)
+	}
+
+	core, err := cat.ThresholdTable("generic_core_type")
+	if err != nil {
+		t.Fatalf("ThresholdTable failed: %v", err)
+	}
+	if len(core.Modifiers) != 0 || len(core.Thresholds) != 2 {
+		t.Errorf("patched threshold table = %+v", core)
+	}
+	if info, ok := cat.Info("new"); !ok || info.Layer != "campaign" {
+		t.Errorf("new asset info = %+v, %v", info, ok)
+	}
+
+	// The built-in layer is not changed by loading overrides.
+	builtin, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	gov, err = builtin.GovernmentTable("generic_gov_terran_hz")
+	if err != nil {
+		t.Fatalf("GovernmentTable failed: %v", err)
+	}
+	if row, _ := gov.Lookup(3); row.Government != "Company" {
+		t.Errorf("built-in row 3 = %+v", row)
+	}
+}
+
+func TestLoadLayersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{"patch without asset", fstest.MapFS{"p.json": {Data: []byte(`{"name": "Nowhere", "patch": true, "data": {"1": "x"}}`)}}, "has no asset to patch"},
+		{"patch changes type", fstest.MapFS{"p.json": {Data: []byte(`{"name": "Planet Core Type", "patch": true, "type": "formula"}`)}}, "changes its type"},
+		{"patch breaks table", fstest.MapFS{"p.json": {Data: []byte(`{"name": "Government (Terran, Habitable Zone)", "patch": true, "data": {"2": null}}`)}}, "has no entry for 2"},
+		{"name twice in a layer", fstest.MapFS{
+			"a.json": {Data: []byte(`{"name": "Twice", "expression": "1d6", "data": {"1-3": "x", "4-6": "y"}}`)},
+			"b.json": {Data: []byte(`{"name": "Twice", "expression": "1d6", "data": {"1-3": "x", "4-6": "y"}}`)},
+		}, `name "Twice" is already used`},
+		{"ID of another asset", fstest.MapFS{"system_quirks.json": {Data: []byte(`{"name": "Other", "expression": "1d6", "data": {"1-3": "x", "4-6": "y"}}`)}}, `asset ID "system_quirks" is already used`},
+		{"no name", fstest.MapFS{"p.json": {Data: []byte(`{"expression": "1d6"}`)}}, "asset has no name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadDefault(Layer{Name: "campaign", FS: tt.files})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadDefault error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), `"campaign:`) {
+				t.Errorf("error %v does not name the layer", err)
+			}
+		})
+	}
+}
+
+// ---------------------------------------------------------------------
+// Structured rows and schemas
+// ---------------------------------------------------------------------
+
+const structuredTable = `{
+  "name": "Patrons",
+  "expression": "1d6",
+  "label": "title",
+  "schema": {
+    "type": "object",
+    "required": ["title", "reward"],
+    "properties": {
+      "title": {"type": "string"},
+      "reward": {"type": "integer", "minimum": 0},
+      "tags": {"type": "array", "items": {"type": "string", "enum": ["legal", "illegal"]}}
+    },
+    "additionalProperties": false
+  },
+  "data": {
+    "1-3": {"title": "Broker", "reward": 1000, "tags": ["legal"]},
+    "4-5": {"title": "Smuggler", "reward": 5000, "tags": ["illegal"]},
+    "6": {"title": "Noble", "reward": 20000}
+  }
+}`
+
+func TestStructuredRows(t *testing.T) {
+	table := GameTable{}
+	if err := json.Unmarshal([]byte(structuredTable), &table); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := table.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if table.Data["4-5"] != "Smuggler" {
+		t.Errorf("label of 4-5 = %q, want Smuggler", table.Data["4-5"])
+	}
+	coll, err := NewCollection("patrons", table)
+	if err != nil {
+		t.Fatalf("NewCollection failed: %v", err)
+	}
+	row, err := coll.Roll(&mockRoller{rollResults: map[string]int{"1d6": 4}}, "Patrons")
+	if err != nil {
+		t.Fatalf("Roll failed: %v", err)
+	}
+	if row.Key != "4-5" || row.String() != "Smuggler" || !row.Structured() {
+		t.Errorf("Roll = %+v", row)
+	}
+	if reward, _ := row.Get("reward"); reward != 5000.0 {
+		t.Errorf("reward = %v, want 5000", reward)
+	}
+	patron := struct {
+		Title  string   `json:"title"`
+		Reward int      `json:"reward"`
+		Tags   []string `json:"tags"`
+	}{}
+	if err := row.Decode(&patron); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if patron.Title != "Smuggler" || patron.Reward != 5000 || !slices.Equal(patron.Tags, []string{"illegal"}) {
+		t.Errorf("Decode = %+v", patron)
+	}
+	// The row is a copy; changing it leaves the table alone.
+	row.Fields["reward"] = 0
+	if table.Rows["4-5"]["reward"] != 5000.0 {
+		t.Error("changing a rolled row changed the table")
+	}
+
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	again := GameTable{}
+	if err := json.Unmarshal(data, &again); err != nil {
+		t.Fatalf("Unmarshal of marshalled table failed: %v", err)
+	}
+	if !reflect.DeepEqual(again, table) {
+		t.Errorf("round trip = %+v, want %+v", again, table)
+	}
+}
+
+func TestStringTableJSONUnchanged(t *testing.T) {
+	table := New("encounters", "1d6", map[string]string{"1-3": "Nothing", "4-6": "Pirates"})
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"name":"encounters","expression":"1d6","data":{"1-3":"Nothing","4-6":"Pirates"},"d_66":false}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	coll, err := NewCollection("c", table)
+	if err != nil {
+		t.Fatalf("NewCollection failed: %v", err)
+	}
+	row, err := coll.Roll(&mockRoller{rollResults: map[string]int{"1d6": 5}}, "encounters")
+	if err != nil || row.Text != "Pirates" || row.Structured() {
+		t.Errorf("Roll = %+v, %v", row, err)
+	}
+	if err := row.Decode(&struct{}{}); err == nil {
+		t.Error("decoding a string row should fail")
+	}
+}
+
+func TestNewRows(t *testing.T) {
+	type world struct {
+		Name string `json:"name"`
+		Size int    `json:"size"`
+	}
+	table, err := NewRows("Worlds", "1d6", "name", map[string]any{
+		"1-2": world{Name: "Regina", Size: 7},
+		"3-4": map[string]any{"name": "Efate", "size": 6},
+		"5-6": "Empty",
+	})
+	if err != nil {
+		t.Fatalf("NewRows failed: %v", err)
+	}
+	if err := table.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if table.Data["1-2"] != "Regina" || table.Data["5-6"] != "Empty" || len(table.Rows) != 2 {
+		t.Errorf("NewRows = %+v", table)
+	}
+	got := world{}
+	if err := table.row("1-2").Decode(&got); err != nil || got.Size != 7 {
+		t.Errorf("Decode = %+v, %v", got, err)
+	}
+	unlabelled, err := NewRows("Plain", "1d6", "", map[string]any{"1-3": map[string]any{"b": 2, "a": 1}, "4-6": "x"})
+	if err != nil {
+		t.Fatalf("NewRows failed: %v", err)
+	}
+	if text := unlabelled.Data["1-3"]; text != `{"a":1,"b":2}` {
+		t.Errorf("unlabelled text = %q", text)
+	}
+	if _, err := NewRows("Bad", "1d6", "", map[string]any{"1-3": 7, "4-6": "x"}); err == nil {
+		t.Error("a number entry should fail")
+	}
+}
+
+func TestStructuredRowsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   func(*GameTable)
+		wantErr string
+	}{
+		{"missing required field", func(g *GameTable) { delete(g.Rows["6"], "reward") }, `entry "6" does not match schema: $: missing required field "reward"`},
+		{"below minimum", func(g *GameTable) { g.Rows["6"]["reward"] = -1.0 }, "$.reward: -1 is below the minimum 0"},
+		{"not an integer", func(g *GameTable) { g.Rows["6"]["reward"] = 1.5 }, "$.reward: 1.5 is not of type integer"},
+		{"enum", func(g *GameTable) { g.Rows["6"]["tags"] = []any{"secret"} }, `$.tags[0]: "secret" is not one of ["legal","illegal"]`},
+		{"unknown field", func(g *GameTable) { g.Rows["6"]["ship"] = "yacht" }, `$: unknown field "ship"`},
+		{"string entry", func(g *GameTable) { delete(g.Rows, "6"); g.Data["6"] = "Noble" }, `$: "Noble" is not of type object`},
+		{"label missing", func(g *GameTable) { g.Label = "name"; g.Data["6"] = g.rowText(g.Rows["6"]) }, "has empty value"},
+		{"row without entry", func(g *GameTable) { g.Rows["7"] = map[string]any{} }, `row "7" without entry`},
+		{"bad schema", func(g *GameTable) { g.Schema.Properties["title"].Pattern = "(" }, "schema $.title"},
+		{"unknown schema type", func(g *GameTable) { g.Schema.Type = "record" }, `unknown type "record"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := GameTable{}
+			if err := json.Unmarshal([]byte(structuredTable), &table); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			tt.patch(&table)
+			err := table.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := json.Unmarshal([]byte(`{"name": "T", "expression": "1d6", "data": {"1-3": 1, "4-6": "x"}}`), &GameTable{}); err == nil {
+		t.Error("a number entry should fail to unmarshal")
+	}
+}
+
+func TestCatalogueGovernmentRows(t *testing.T) {
+	cat, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	coll, err := cat.Collection("assets")
+	if err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+	row, err := coll.Roll(&mockRoller{rollResults: map[string]int{"2d6": 3}}, "Government (Terran, Habitable Zone)")
+	if err != nil {
+		t.Fatalf("Roll failed: %v", err)
+	}
+	gov := GovernmentRow{}
+	if err := row.Decode(&gov); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if row.Text != "Company" || gov != (GovernmentRow{Code: "1", Government: "Company", LawLevel: "2d6-1"}) {
+		t.Errorf("Roll = %+v, decoded %+v", row, gov)
+	}
+}
+
+// ---------------------------------------------------------------------
+// Inline rolls and table references
+// ---------------------------------------------------------------------
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []segment
+		wantErr bool
+	}{
+		{"plain", []segment{{text: "plain"}}, false},
+		{"{2d6-1} crew", []segment{{expr: "2d6-1"}, {text: " crew"}}, false},
+		{"A { @Ship Type } and {{braces}}", []segment{{text: "A "}, {ref: "Ship Type"}, {text: " and {braces}"}}, false},
+		{"", nil, false},
+		{"{}", nil, true},
+		{"{@}", nil, true},
+		{"{2d6", nil, true},
+		{"2d6}", nil, true},
+		{"{{2d6}", nil, true},
+		{"{a{b}}", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTemplate(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTemplate(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("parseTemplate(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCollectionRollResolves(t *testing.T) {
+	coll, err := NewCollection("resolve",
+		New("Encounter", "1d6", map[string]string{"1-3": "{1d6} pirates", "4-6": "{@Ship} with {@Crew}"}),
+		New("Ship", "2d6", map[string]string{"2-7": "Trader {{free}}", "8-12": "Corsair"}),
+		New("Crew", "1d3", map[string]string{"1": "none", "2-3": "{D66} crew ({@Mood})"}),
+		New("Mood", "1d6", map[string]string{"1-3": "calm", "4-6": "angry"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	roller := &mockRoller{d66Result: "23", rollResults: map[string]int{"1d6": 5, "2d6": 4, "1d3": 2}}
+	row, err := coll.Roll(roller, "Encounter")
+	if err != nil {
+		t.Fatalf("Roll failed: %v", err)
+	}
+	if want := "Trader {free} with 23 crew (angry)"; row.Text != want {
+		t.Errorf("Roll = %q, want %q", row.Text, want)
+	}
+	want := []Step{
+		{Depth: 0, Table: "Encounter", Placeholder: "{@Ship}", Value: "Trader {free}"},
+		{Depth: 0, Table: "Encounter", Placeholder: "{@Crew}", Value: "23 crew (angry)"},
+		{Depth: 1, Table: "Crew", Placeholder: "{D66}", Value: "23"},
+		{Depth: 1, Table: "Crew", Placeholder: "{@Mood}", Value: "angry"},
+	}
+	if !slices.Equal(row.Trace, want) {
+		t.Errorf("Trace = %+v, want %+v", row.Trace, want)
+	}
+	if want := []string{"Encounter", "Ship", "Crew", "Mood"}; !slices.Equal(coll.rollSequence, want) {
+		t.Errorf("rollSequence = %v, want %v", coll.rollSequence, want)
+	}
+
+	roller.rollResults["1d6"] = 2
+	if row, err := coll.Roll(roller, "Encounter"); err != nil || row.Text != "2 pirates" {
+		t.Errorf("Roll = %q, %v, want 2 pirates", row.Text, err)
+	}
+}
+
+func TestCollectionRollResolvesRows(t *testing.T) {
+	gov, err := NewRows("Government", "1d6", "government", map[string]any{
+		"1-3": map[string]any{"code": "1", "government": "Company", "law_level": "{2d6-1}"},
+		"4-6": map[string]any{"code": "{@Code}", "government": "Roll normally ({@Code})", "law_level": "Normal"},
+	})
+	if err != nil {
+		t.Fatalf("NewRows failed: %v", err)
+	}
+	coll, err := NewCollection("gov", gov, New("Code", "1d3", map[string]string{"1": "7", "2-3": "9"}))
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	roller := &mockRoller{rollResults: map[string]int{"1d6": 1, "2d6-1": 8, "1d3": 1}}
+	row, err := coll.Roll(roller, "Government")
+	if err != nil {
+		t.Fatalf("Roll failed: %v", err)
+	}
+	if law, _ := row.Get("law_level"); law != "8" || row.Text != "Company" {
+		t.Errorf("Roll = %+v", row)
+	}
+	if gov.Rows["1-3"]["law_level"] != "{2d6-1}" {
+		t.Error("resolving a row changed the table")
+	}
+	roller.rollResults["1d6"] = 6
+	row, err = coll.Roll(roller, "Government")
+	if err != nil {
+		t.Fatalf("Roll failed: %v", err)
+	}
+	if code, _ := row.Get("code"); code != "7" || row.Text != "Roll normally (7)" || len(row.Trace) != 2 {
+		t.Errorf("Roll = %+v", row)
+	}
+}
+
+func TestCollectionRollLoops(t *testing.T) {
+	coll, err := NewCollection("loop",
+		New("A", "1d6", map[string]string{"1-3": "{@B}", "4-6": "{@B}!"}),
+		New("B", "1d6", map[string]string{"1-3": "then {@A}", "4-6": "and {@A}"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	_, err = coll.Roll(&mockRoller{rollResults: map[string]int{"1d6": 2}}, "A")
+	if !errors.Is(err, ErrResolveLoop) || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("Roll error = %v, want a loop A -> B -> A", err)
+	}
+
+	// A table may refer to itself: "roll twice" ends as soon as both rolls land
+	// elsewhere.
+	twice, err := NewCollection("twice", New("Loot", "1d6", map[string]string{"1-5": "coins", "6": "{@Loot} and {@Loot}"}))
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	m, err := dice.New("roll twice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 200 {
+		row, err := twice.Roll(m, "Loot")
+		if err != nil {
+			t.Fatalf("Roll failed: %v", err)
+		}
+		if n := strings.Count(row.Text, "coins"); n != strings.Count(row.Text, " and ")+1 {
+			t.Fatalf("Roll = %q", row.Text)
+		}
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	if err := New("T", "1d6", map[string]string{"1-3": "{2d6", "4-6": "x"}).Validate(); err == nil || !strings.Contains(err.Error(), "unclosed") {
+		t.Errorf("Validate error = %v, want unclosed brace", err)
+	}
+	if err := New("T", "1d6", map[string]string{"1-3": "{2x6} crew", "4-6": "x"}).Validate(); err == nil || !strings.Contains(err.Error(), `inline roll "{2x6}"`) {
+		t.Errorf("Validate error = %v, want a bad inline roll", err)
+	}
+	rows, err := NewRows("R", "1d6", "", map[string]any{"1-3": map[string]any{"tags": []any{"{@Nowhere}"}}, "4-6": "x"})
+	if err != nil {
+		t.Fatalf("NewRows failed: %v", err)
+	}
+	_, err = NewCollection("refs", rows)
+	if err == nil || !strings.Contains(err.Error(), `table "R" refers to unknown table "Nowhere"`) {
+		t.Errorf("NewCollection error = %v, want an unknown reference", err)
+	}
+}
+
+func TestCollectionForceResolves(t *testing.T) {
+	coll, err := NewCollection("force",
+		New("Encounter", "2d6", map[string]string{"2-6": "Nothing", "7-12": "{@Ship}"}),
+		New("Ship", "1d6", map[string]string{"1-3": "Trader", "4-6": "Corsair"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	m, err := dice.New("force resolves")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, err := coll.Force(m, "Encounter", "{@Ship}")
+	if err != nil {
+		t.Fatalf("Force failed: %v", err)
+	}
+	if row.Text != "Trader" && row.Text != "Corsair" {
+		t.Errorf("Force = %q, want a ship", row.Text)
+	}
+}
+
+// ---------------------------------------------------------------------
+// Conditions and modifier tables
+// ---------------------------------------------------------------------
+
+// sumRoller returns the fixed result of an expression plus the mods it is given.
+type sumRoller struct {
+	mockRoller
+	mods []int
+}
+
+func (m *sumRoller) Roll(expr string, mods ...int) (int, error) {
+	m.mods = mods
+	total, err := m.mockRoller.Roll(expr)
+	for _, mod := range mods {
+		total += mod
+	}
+	return total, err
+}
+
+func TestParseCondition(t *testing.T) {
+	attrs := Attributes{"Atmosphere": 5, "Size": int64(16), "Temperature": -12.5, "Star": "G", "Oxygen": true}
+	tests := []struct {
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		{"Atmosphere in 4..5", true, false},
+		{"Atmosphere in [3, 6..9]", false, false},
+		{"Size >= 15 and Size <= 19", true, false},
+		{"Temperature < 0 or Temperature > 40", true, false},
+		{"Star == 'G' and not (Star != \"G\")", true, false},
+		{"Star in ['O', 'B', 'A', 'M']", false, false},
+		{"Oxygen", true, false},
+		{"not Oxygen or Atmosphere == 5", true, false},
+		{"Oxygen == false", false, false},
+		{"Atmosphere > 3 or Size < 0 and Oxygen == false", true, false}, // and binds tighter
+		{"Hydrographics > 0", false, true},                              // not set
+		{"Star > 'A'", false, true},
+		{"Atmosphere == 'A'", false, true},
+		{"Atmosphere", false, true},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.cond)
+		if err != nil {
+			t.Errorf("ParseCondition(%q) failed: %v", tt.cond, err)
+			continue
+		}
+		got, err := c.Eval(attrs)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Eval(%q) = %v, %v, want %v (error %v)", tt.cond, got, err, tt.want, tt.wantErr)
+		}
+	}
+	for _, bad := range []string{"", "Atmosphere in", "Atmosphere in 5..4", "Atmosphere in [4", "(Size > 1", "Size >", "Size = 1", "and Size", "Size > 1 Size", "Star == 'G"} {
+		if _, err := ParseCondition(bad); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want an error", bad)
+		}
+	}
+	c, err := ParseCondition("Size > 5 and (Zone == 'inner' or Size < 9)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Size", "Zone"}; !slices.Equal(c.Attributes(), want) {
+		t.Errorf("Attributes = %v, want %v", c.Attributes(), want)
+	}
+	// Every attribute must be set, even one the result does not depend on.
+	if _, err := c.Eval(Attributes{"Size": 1}); err == nil || !strings.Contains(err.Error(), `attribute "Zone" is not set`) {
+		t.Errorf("Eval error = %v, want Zone not set", err)
+	}
+}
+
+func TestModifierTableApply(t *testing.T) {
+	cat, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	bio, err := cat.ModifierTable("generic_biology_modifiers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := bio.Apply(Attributes{
+		"Oxygen": true, "Atmosphere": 6, "Hydrographics": 10, "Temperature": 15,
+		"Zone": "habitable", "Star": "G",
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	var keys []string
+	for _, am := range e.Applied {
+		keys = append(keys, am.Key)
+	}
+	if want := []string{"atmosphere_standard", "hydrosphere_marginal", "star_type_solar"}; !slices.Equal(keys, want) || e.Total != 15 {
+		t.Errorf("Apply = %v %+d, want %v +15", keys, e.Total, want)
+	}
+	if want := "Atmosphere code 6-9: +10; Hydrosphere 3-4 or 10(A): -5; Star is G-class: +10; DM +15"; e.String() != want {
+		t.Errorf("String = %q, want %q", e.String(), want)
+	}
+	if _, err := bio.Apply(Attributes{"Atmosphere": 6}); err == nil {
+		t.Error("Apply without every attribute succeeded")
+	}
+
+	core, err := cat.ThresholdTable("generic_core_type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, e, err := core.Roll(&sumRoller{mockRoller: mockRoller{rollResults: map[string]int{"2d6": 8}}}, Attributes{"Size": 3, "Zone": "beyond_snow_line"})
+	if err != nil || result != "Icy" || e.Total != 9 {
+		t.Errorf("Roll = %q %+d, %v, want Icy +9", result, e.Total, err)
+	}
+
+	noWhen := ModifierTable{Name: "prose", Modifiers: map[string]Modifier{"a": {Condition: "Star is hot", Value: 1}}}
+	if err := noWhen.Validate(); err != nil {
+		t.Errorf("Validate = %v, a modifier without when is documentation", err)
+	}
+	if _, err := noWhen.Apply(Attributes{}); err == nil || !strings.Contains(err.Error(), `no "when" condition`) {
+		t.Errorf("Apply error = %v, want no when", err)
+	}
+	bad := ModifierTable{Name: "bad", Modifiers: map[string]Modifier{"a": {Condition: "x", When: "Size >", Value: 1}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate accepted an unparseable when")
+	}
+}
+
+func TestCollectionRollWith(t *testing.T) {
+	cat, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault failed: %v", err)
+	}
+	hydro, err := cat.ModifierTable("generic_superterran_hydro_modifiers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coll, err := NewCollection("hydro",
+		New("Hydrographics", "1d6", map[string]string{"1-": "Dry", "2-4": "Wet", "5+": "Ocean"}),
+		New("World", "1d6", map[string]string{"1-3": "{@Hydrographics} world", "4-6": "Barren"}),
+		New("Code", "D66", map[string]string{"11-36": "A", "41-66": "B"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	if err := coll.AddModifiers("Hydrographics", hydro); err != nil {
+		t.Fatalf("AddModifiers failed: %v", err)
+	}
+	roller := &sumRoller{mockRoller: mockRoller{rollResults: map[string]int{"1d6": 2}}}
+	row, err := coll.RollWith(roller, "Hydrographics", Attributes{"Size": 21, "Atmosphere": 6}, -1)
+	if err != nil {
+		t.Fatalf("RollWith failed: %v", err)
+	}
+	if row.Text != "Ocean" || row.Modifiers.Total != 4 || len(row.Modifiers.Applied) != 2 || !slices.Equal(roller.mods, []int{-1, 4}) {
+		t.Errorf("RollWith = %q %+v, mods %v", row.Text, row.Modifiers, roller.mods)
+	}
+
+	// Roll does not apply the modifiers; references roll with the attributes.
+	if row, err := coll.Roll(roller, "Hydrographics"); err != nil || row.Text != "Wet" || len(row.Modifiers.Applied) != 0 {
+		t.Errorf("Roll = %q %+v, %v, want Wet without DMs", row.Text, row.Modifiers, err)
+	}
+	roller.rollResults["1d6"] = 1
+	if row, err := coll.RollWith(roller, "World", Attributes{"Size": 8, "Atmosphere": 4}); err != nil || row.Text != "Dry world" {
+		t.Errorf("RollWith = %q, %v, want Dry world", row.Text, err)
+	}
+	if _, err := coll.RollWith(roller, "Hydrographics", Attributes{"Size": 8}); err == nil || !strings.Contains(err.Error(), `attribute "Atmosphere" is not set`) {
+		t.Errorf("RollWith error = %v, want Atmosphere not set", err)
+	}
+
+	if err := coll.AddModifiers("Code", hydro); err == nil {
+		t.Error("AddModifiers on a D66 table succeeded")
+	}
+	if err := coll.AddModifiers("Nowhere", hydro); err == nil {
+		t.Error("AddModifiers on an unknown table succeeded")
+	}
 }
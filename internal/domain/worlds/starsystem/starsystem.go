@@ -30,7 +30,7 @@ func DetermineSystemObject(dp *dice.Manager) SystemObject {
 	if err != nil {
 		panic(0)
 	}
-	return SystemObject(ot)
+	return SystemObject(ot.Text)
 }
 
 const (